@@ -7,13 +7,21 @@ import (
 	"io"
 	"io/fs"
 	"strings"
+	"sync"
 
 	"github.com/evanw/esbuild/pkg/api"
 	"github.com/typomaker/flow"
 )
 
 func Build(ctx context.Context, path string) (content []byte, err error) {
+	return Trace(ctx, path, func(string) {})
+}
+
+// Trace builds like Build and calls record with every FS file it is about
+// to read.
+func Trace(ctx context.Context, path string, record func(path string)) (content []byte, err error) {
 	var flowctx = flow.Context(ctx)
+	record(path)
 	var file fs.File
 	if file, err = flowctx.FS().Open(path); err != nil {
 		return nil, fmt.Errorf("build: %w", err)
@@ -28,6 +36,7 @@ func Build(ctx context.Context, path string) (content []byte, err error) {
 		return nil, fmt.Errorf("build: %w", err)
 	}
 
+	var mu sync.Mutex
 	var r = api.Build(api.BuildOptions{
 		Stdin: &api.StdinOptions{
 			Sourcefile: path,
@@ -44,7 +53,11 @@ func Build(ctx context.Context, path string) (content []byte, err error) {
 		PreserveSymlinks: true,
 		Plugins: []api.Plugin{
 			newImportHTTP(ctx),
-			newImportFlow(ctx),
+			newImportFlow(ctx, func(path string) {
+				mu.Lock()
+				record(path)
+				mu.Unlock()
+			}),
 		},
 	})
 	if len(r.Errors) != 0 {
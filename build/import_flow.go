@@ -5,14 +5,18 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"maps"
 	"net/url"
+	"path"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/evanw/esbuild/pkg/api"
 	"github.com/typomaker/flow"
 )
 
-func newImportFlow(ctx context.Context) api.Plugin {
+func newImportFlow(ctx context.Context, record func(path string)) api.Plugin {
 	const namespace = "import-flow"
 	var flowctx = flow.Context(ctx)
 	return api.Plugin{
@@ -47,9 +51,14 @@ func newImportFlow(ctx context.Context) api.Plugin {
 			build.OnLoad(
 				api.OnLoadOptions{Filter: ".*", Namespace: namespace},
 				func(args api.OnLoadArgs) (r api.OnLoadResult, err error) {
-					var path = strings.TrimPrefix(args.Path, "flow:")
+					if args.Path == "flow:"+HostModule {
+						var content = hostModule(flowctx.Host())
+						return api.OnLoadResult{Contents: &content}, nil
+					}
+					var name = path.Clean(strings.TrimPrefix(args.Path, "flow:"))
+					record(name)
 					var fsfile fs.File
-					if fsfile, err = flowctx.FS().Open(path); err != nil {
+					if fsfile, err = flowctx.FS().Open(name); err != nil {
 						return r, fmt.Errorf("%s: %w", namespace, err)
 					}
 					var fsinfo fs.FileInfo
@@ -70,3 +79,34 @@ func newImportFlow(ctx context.Context) api.Plugin {
 		},
 	}
 }
+
+// HostModule is the name flow:host imports flow.Host under. Only that exact
+// specifier is reserved, an FS module named host stays reachable as
+// flow:./host.
+const HostModule = "host"
+const HostGlobal = "__flow_host__"
+
+var hostIdent = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func hostModule(h flow.Host) string {
+	var b strings.Builder
+	b.WriteString(`
+const current = () => globalThis.` + HostGlobal + `
+const bind = (name) => {
+	const value = current()[name]
+	if (typeof value === "function") {
+		return (...args) => current()[name](...args)
+	}
+	if (typeof value === "object" && value !== null) {
+		return new Proxy({}, {get: (_, key) => current()[name][key]})
+	}
+	return value
+}
+`)
+	for _, name := range slices.Sorted(maps.Keys(h)) {
+		if hostIdent.MatchString(name) {
+			fmt.Fprintf(&b, "export const %s = bind(%q)\n", name, name)
+		}
+	}
+	return b.String()
+}
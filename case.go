@@ -1,7 +1,10 @@
 package flow
 
 import (
+	"fmt"
 	"log/slog"
+
+	jsoniter "github.com/json-iterator/go"
 )
 
 type Case struct {
@@ -34,3 +37,32 @@ func (it Case) LogValue() slog.Value {
 		slog.Any("then", it.Then),
 	)
 }
+
+type _CaseJSON struct {
+	When jsoniter.RawMessage `json:"when,omitempty"`
+	Then jsoniter.RawMessage `json:"then,omitempty"`
+}
+
+func (it Case) MarshalJSON() (b []byte, err error) {
+	var js _CaseJSON
+	if js.When, err = jsoniter.Marshal(it.When); err != nil {
+		return nil, fmt.Errorf("when: %w", err)
+	}
+	if js.Then, err = jsoniter.Marshal(it.Then); err != nil {
+		return nil, fmt.Errorf("then: %w", err)
+	}
+	return jsoniter.Marshal(js)
+}
+func (it *Case) UnmarshalJSON(b []byte) (err error) {
+	var js _CaseJSON
+	if err = jsoniter.Unmarshal(b, &js); err != nil {
+		return err
+	}
+	if err = unmarshalRaw(js.When, &it.When); err != nil {
+		return fmt.Errorf("when: %w", err)
+	}
+	if err = unmarshalRaw(js.Then, &it.Then); err != nil {
+		return fmt.Errorf("then: %w", err)
+	}
+	return nil
+}
@@ -50,3 +50,26 @@ func TestCaseLog(t *testing.T) {
 		b.Reset()
 	})
 }
+func TestCaseJSON(t *testing.T) {
+	v := Case{
+		When: When{UUID: option.Some([]UUID{MustUUID("eaeb5a25-21e5-47ff-a142-7a9987f2e3f0")})},
+		Then: Then{
+			Kind: option.Some("foo"),
+			Meta: option.Some(Meta{"a": "1"}),
+			Hook: option.Some(Hook{"b": "2"}),
+		},
+	}
+	b, err := v.MarshalJSON()
+	require.NoError(t, err)
+	require.JSONEq(t,
+		`{
+			"when":{"uuid":["eaeb5a25-21e5-47ff-a142-7a9987f2e3f0"]},
+			"then":{"kind":"foo","meta":{"a":"1"},"hook":{"b":"2"}}
+		}`,
+		string(b),
+	)
+	e := Case{}
+	err = e.UnmarshalJSON(b)
+	require.NoError(t, err)
+	require.True(t, v.Equal(e))
+}
@@ -0,0 +1,141 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"gopkg.in/yaml.v3"
+)
+
+type Runtime map[string]func(path string) Handler
+
+func Config(path string, r Runtime) Handler {
+	var mu sync.Mutex
+	var h Handler
+	return func(ctx context.Context, target []Node, next Next) (err error) {
+		mu.Lock()
+		if h == nil {
+			if h, err = LoadConfig(Context(ctx).FS(), path, r); err != nil {
+				mu.Unlock()
+				return err
+			}
+		}
+		mu.Unlock()
+		return h(ctx, target, next)
+	}
+}
+func LoadConfig(fsys fs.FS, path string, r Runtime) (_ Handler, err error) {
+	var b []byte
+	if b, err = fs.ReadFile(fsys, path); err != nil {
+		return nil, fmt.Errorf("flow: config: %w", err)
+	}
+	var doc any
+	if err = yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("flow: config %s: %w", path, err)
+	}
+	if b, err = jsoniter.Marshal(doc); err != nil {
+		return nil, fmt.Errorf("flow: config %s: %w", path, err)
+	}
+	var h Handler
+	if h, err = r.handler(b); err != nil {
+		return nil, fmt.Errorf("flow: config %s: %w", path, err)
+	}
+	return h, nil
+}
+func (it Runtime) handler(b jsoniter.RawMessage) (_ Handler, err error) {
+	var js map[string]jsoniter.RawMessage
+	if err = jsoniter.Unmarshal(b, &js); err != nil {
+		return nil, err
+	}
+	if len(js) != 1 {
+		return nil, fmt.Errorf("expected one key, got %d", len(js))
+	}
+	var h Handler
+	for k, v := range js {
+		if h, err = it.handlerOf(k, v); err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	return h, nil
+}
+func (it Runtime) handlerOf(key string, b jsoniter.RawMessage) (_ Handler, err error) {
+	switch key {
+	case "pipe", "and", "or":
+		var hs []Handler
+		if hs, err = it.handlers(b); err != nil {
+			return nil, err
+		}
+		switch key {
+		case "pipe":
+			return Pipe(hs...), nil
+		case "and":
+			return And(hs...), nil
+		default:
+			return Or(hs...), nil
+		}
+	case "not", "always", "never":
+		var h Handler
+		if h, err = it.handler(b); err != nil {
+			return nil, err
+		}
+		switch key {
+		case "not":
+			return Not(h), nil
+		case "always":
+			return Always(h), nil
+		default:
+			return Never(h), nil
+		}
+	case "uuid":
+		var w When
+		if err = jsoniter.Unmarshal(b, &w.UUID); err != nil {
+			return nil, err
+		}
+		return w.handler(), nil
+	case "hook":
+		var w When
+		if err = jsoniter.Unmarshal(b, &w.Hook); err != nil {
+			return nil, err
+		}
+		return w.handler(), nil
+	case "when":
+		var w When
+		if err = jsoniter.Unmarshal(b, &w); err != nil {
+			return nil, err
+		}
+		return w.handler(), nil
+	}
+	var r, ok = it[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown handler")
+	}
+	var path string
+	if err = jsoniter.Unmarshal(b, &path); err != nil {
+		return nil, err
+	}
+	return r(path), nil
+}
+func (it Runtime) handlers(b jsoniter.RawMessage) (hs []Handler, err error) {
+	var js []jsoniter.RawMessage
+	if err = jsoniter.Unmarshal(b, &js); err != nil {
+		return nil, err
+	}
+	hs = make([]Handler, len(js))
+	for i := range js {
+		if hs[i], err = it.handler(js[i]); err != nil {
+			return nil, fmt.Errorf("%d: %w", i, err)
+		}
+	}
+	return hs, nil
+}
+
+func (it When) handler() Handler {
+	return func(ctx context.Context, target []Node, next Next) (err error) {
+		return nextIf(target, next, func(n Node) bool {
+			return n.When(it)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package flow
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/typomaker/option"
+)
+
+func TestConfig(t *testing.T) {
+	var seen []string
+	r := Runtime{
+		"mark": func(path string) Handler {
+			return func(ctx context.Context, target []Node, next Next) (err error) {
+				for i := range target {
+					seen = append(seen, path)
+					target[i].Meta = option.Some(Meta{"path": path})
+				}
+				return next(target)
+			}
+		},
+	}
+	f := New(
+		FS(fstest.MapFS{
+			"flow.yaml": &fstest.MapFile{Data: []byte(`
+pipe:
+  - or:
+      - pipe:
+          - uuid: ["10000000-0000-0000-0000-000000000000"]
+          - mark: uuid.js
+      - pipe:
+          - hook: [{kind: cat}]
+          - mark: cat.js
+      - pipe:
+          - not: {hook: [{kind: cat}]}
+          - when: {hook: [{kind: dog}]}
+          - mark: dog.js
+  - always:
+      never:
+        mark: never.js
+`)},
+		}),
+		Config("flow.yaml", r),
+	)
+	target := []Node{
+		{UUID: option.Some(MustUUID("10000000-0000-0000-0000-000000000000"))},
+		{Hook: option.Some(Hook{"kind": "cat"})},
+		{Hook: option.Some(Hook{"kind": "dog"})},
+	}
+	for i := range target {
+		err := f.Run(context.Background(), target[i:i+1])
+		require.NoError(t, err)
+	}
+	require.Equal(t, []string{"uuid.js", "never.js", "cat.js", "never.js", "dog.js", "never.js"}, seen)
+	require.Equal(t, "never.js", target[2].Meta.Get()["path"])
+}
+func TestLoadConfig(t *testing.T) {
+	fsys := fstest.MapFS{
+		"flow.json":    &fstest.MapFile{Data: []byte(`{"pipe": [{"hook": [{"kind": "cat"}]}, {"uuid": []}]}`)},
+		"unknown.yaml": &fstest.MapFile{Data: []byte("pipe:\n  - foo: bar.js\n")},
+		"many.yaml":    &fstest.MapFile{Data: []byte("not: {uuid: []}\nnever: {uuid: []}\n")},
+		"broken.yaml":  &fstest.MapFile{Data: []byte("uuid: [foo]\n")},
+	}
+	h, err := LoadConfig(fsys, "flow.json", Runtime{})
+	require.NoError(t, err)
+	var passed []Node
+	err = h(context.Background(), []Node{{Hook: option.Some(Hook{"kind": "cat"})}}, func(target []Node) error {
+		passed = target
+		return nil
+	})
+	require.NoError(t, err)
+	require.Empty(t, passed)
+
+	_, err = LoadConfig(fsys, "unknown.yaml", Runtime{})
+	require.EqualError(t, err, "flow: config unknown.yaml: pipe: 0: foo: unknown handler")
+	_, err = LoadConfig(fsys, "many.yaml", Runtime{})
+	require.EqualError(t, err, "flow: config many.yaml: expected one key, got 2")
+	_, err = LoadConfig(fsys, "broken.yaml", Runtime{})
+	require.Error(t, err)
+	_, err = LoadConfig(fsys, "missing.yaml", Runtime{})
+	require.Error(t, err)
+}
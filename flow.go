@@ -2,9 +2,20 @@ package flow
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io/fs"
 	"log/slog"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"runtime/debug"
 	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/laher/mergefs"
@@ -20,6 +31,8 @@ func New(o ...Setup) (f Flow) {
 	f.logger = s.Logger
 	f.handler = s.Handler
 	f.extension = slices.Clip(s.Extension)
+	f.fetch = s.Fetch
+	f.host = s.Host
 	return f
 }
 
@@ -28,12 +41,16 @@ type Flow struct {
 	logger    *slog.Logger
 	handler   Handler
 	extension []LogAttrer
+	fetch     Fetch
+	host      Host
 }
 
 func (it Flow) setup(s *Setting) {
 	s.FS = it.fs
 	s.Logger = it.logger
 	s.Handler = it.handler
+	s.Fetch = it.fetch
+	s.Host = it.host
 }
 
 type Setup interface {
@@ -44,6 +61,8 @@ type Setting struct {
 	Logger    *slog.Logger
 	Handler   Handler
 	Extension []LogAttrer
+	Fetch     Fetch
+	Host      Host
 }
 
 func FS(f fs.FS) Setup {
@@ -101,6 +120,72 @@ func Extension(l ...LogAttrer) Setup {
 func (it Flow) Extension() []LogAttrer {
 	return it.extension
 }
+
+const DefaultFetchLimit = 10 << 20
+
+type Fetch struct {
+	Client  *http.Client
+	Allow   []string
+	Timeout time.Duration
+	// Limit caps the response body in bytes, DefaultFetchLimit when zero.
+	Limit int64
+}
+
+func (it Fetch) setup(s *Setting) {
+	s.Fetch = it
+}
+func (it Fetch) Allowed(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	return slices.ContainsFunc(it.Allow, func(host string) bool {
+		return strings.EqualFold(host, u.Host) || strings.EqualFold(host, u.Hostname())
+	})
+}
+func (it Fetch) BodyLimit() int64 {
+	if it.Limit > 0 {
+		return it.Limit
+	}
+	return DefaultFetchLimit
+}
+func (it Fetch) HTTPClient() *http.Client {
+	var c http.Client
+	if it.Client != nil {
+		c = *it.Client
+	}
+	var check = c.CheckRedirect
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		switch {
+		case !it.Allowed(req.URL):
+			return fmt.Errorf("flow: redirect to %q is not allowed", req.URL.Host)
+		case check != nil:
+			return check(req, via)
+		case len(via) >= 10:
+			return errors.New("flow: stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &c
+}
+func (it Flow) Fetch() Fetch {
+	return it.fetch
+}
+
+type HostFunc func(ctx context.Context, args []any) (any, error)
+type Host map[string]any
+
+func (it Host) setup(s *Setting) {
+	if len(it) == 0 {
+		return
+	}
+	var h = make(Host, len(s.Host)+len(it))
+	maps.Copy(h, s.Host)
+	maps.Copy(h, it)
+	s.Host = h
+}
+func (it Flow) Host() Host {
+	return it.host
+}
 func (it Flow) Run(ctx context.Context, target []Node, extension ...LogAttrer) (err error) {
 	if it.handler == nil {
 		return
@@ -145,19 +230,20 @@ func And(hs ...Handler) Handler {
 		}
 	}
 	return func(ctx context.Context, target []Node, next Next) (err error) {
+		var rest = hs
 		var step Next
 		step = func(target []Node) error {
-			if len(hs) == 0 {
+			if len(rest) == 0 {
 				return next(target)
 			}
-			var h = hs[0]
-			hs = hs[1:]
+			var h = rest[0]
+			rest = rest[1:]
 			return h(ctx, target, step)
 		}
 		if err = step(target); err != nil {
 			return err
 		}
-		if len(hs) != 0 {
+		if len(rest) != 0 {
 			return next(target)
 		}
 		return
@@ -224,6 +310,338 @@ func Never(h Handler) Handler {
 	}
 }
 
+func Parallel(hs ...Handler) Handler {
+	return ParallelN(0, hs...)
+}
+
+// ParallelN runs every branch on its own copy of the target and merges the
+// copies back by position once all branches are done. A node changed by
+// several branches takes the change of the last of them in argument order.
+func ParallelN(n int, hs ...Handler) Handler {
+	if n <= 0 || n > len(hs) {
+		n = len(hs)
+	}
+	return func(ctx context.Context, target []Node, next Next) (err error) {
+		var wg sync.WaitGroup
+		var sem = make(chan struct{}, max(n, 1))
+		var errs = make([]error, len(hs))
+		var done = make([][]Node, len(hs))
+		for i, h := range hs {
+			var cp = make([]Node, len(target))
+			for j := range target {
+				cp[j] = target[j].Copy()
+			}
+			done[i] = cp
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				errs[i] = h(ctx, cp, noopNext)
+			}()
+		}
+		wg.Wait()
+		if err = errors.Join(errs...); err != nil {
+			return err
+		}
+		for j := range target {
+			var n = target[j]
+			for i := range done {
+				if !done[i][j].Equal(target[j]) {
+					n = done[i][j]
+				}
+			}
+			target[j] = n
+		}
+		return next(target)
+	}
+}
+
+// Batch maps the nodes passed to next back onto the chunk by position when
+// next gets the chunk itself, a window of it, or all of its nodes in order.
+// Otherwise the nodes are matched by UUID, so a handler that reorders or
+// filters must pass nodes with a UUID unique within the target.
+func Batch(size int, h Handler) Handler {
+	return BatchN(size, 1, h)
+}
+func BatchN(size, n int, h Handler) Handler {
+	if size <= 0 {
+		return h
+	}
+	return func(ctx context.Context, target []Node, next Next) (err error) {
+		var count = (len(target) + size - 1) / size
+		var pass = make([]bool, len(target))
+		var errs = make([]error, count)
+		var wg sync.WaitGroup
+		var sem = make(chan struct{}, max(n, 1))
+		for i := range count {
+			var lo, hi = i * size, min((i+1)*size, len(target))
+			var part = target[lo:hi:hi]
+			var run = func() {
+				errs[i] = h(ctx, part, func(v []Node) error {
+					return mergeBatch(part, v, pass[lo:hi])
+				})
+			}
+			if n <= 1 {
+				if run(); errs[i] != nil {
+					return errs[i]
+				}
+				continue
+			}
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				run()
+			}()
+		}
+		wg.Wait()
+		if err = errors.Join(errs...); err != nil {
+			return err
+		}
+		if !slices.Contains(pass, false) {
+			return next(target)
+		}
+		var out = make([]Node, 0, len(target))
+		for i := range target {
+			if pass[i] {
+				out = append(out, target[i])
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return next(out)
+	}
+}
+func mergeBatch(part, v []Node, pass []bool) error {
+	if len(v) == 0 {
+		return nil
+	}
+	for k := range part {
+		if &part[k] == &v[0] && k+len(v) <= len(part) {
+			for j := range v {
+				pass[k+j] = true
+			}
+			return nil
+		}
+	}
+	if samePosition(part, v) {
+		copy(part, v)
+		for i := range pass {
+			pass[i] = true
+		}
+		return nil
+	}
+	var index = make(map[UUID]int, len(part))
+	for i := range part {
+		if part[i].UUID.IsSome() {
+			index[part[i].UUID.Get()] = i
+		}
+	}
+	for j := range v {
+		var i, ok = index[v[j].UUID.GetOrZero()]
+		if !ok || !v[j].UUID.IsSome() {
+			return fmt.Errorf("flow: batch: next got node %d without a uuid from the chunk", j)
+		}
+		part[i] = v[j]
+		pass[i] = true
+	}
+	return nil
+}
+func samePosition(part, v []Node) bool {
+	if len(part) != len(v) {
+		return false
+	}
+	for i := range part {
+		if part[i].UUID != v[i].UUID {
+			return false
+		}
+	}
+	return true
+}
+
+func Timeout(d time.Duration, h Handler) Handler {
+	return TimeoutNamed("", d, h)
+}
+
+// TimeoutNamed runs h in its own goroutine on a copy of the target and
+// returns a TimeoutError once d passes, even if h ignores ctx. The error
+// names the handler by name, by SetTimeoutName from h, or by the pipe name.
+func TimeoutNamed(name string, d time.Duration, h Handler) Handler {
+	return func(ctx context.Context, target []Node, next Next) (err error) {
+		var cause = &TimeoutError{After: d}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, d, cause)
+		defer cancel()
+		var running = new(atomic.Pointer[string])
+		ctx = context.WithValue(ctx, contextTimeoutKey{}, running)
+
+		var mu sync.Mutex
+		var expired bool
+		var cp = make([]Node, len(target))
+		for i := range target {
+			cp[i] = target[i].Copy()
+		}
+		var done = make(chan error, 1)
+		go func() {
+			done <- h(ctx, cp, func(v []Node) error {
+				mu.Lock()
+				defer mu.Unlock()
+				if expired {
+					return context.Cause(ctx)
+				}
+				return next(v)
+			})
+		}()
+		select {
+		case err = <-done:
+			copy(target, cp)
+		case <-ctx.Done():
+			mu.Lock()
+			expired = true
+			mu.Unlock()
+			err = context.Cause(ctx)
+		}
+		if err == nil || context.Cause(ctx) != cause {
+			return err
+		}
+		var timeout *TimeoutError
+		if errors.As(err, &timeout) && timeout != cause {
+			return err
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			cause.Handler = timeoutName(ctx, name, running)
+			return cause
+		}
+		return err
+	}
+}
+
+type contextTimeoutKey struct{}
+
+// SetTimeoutName names the handler running under ctx in the TimeoutError of
+// an enclosing Timeout.
+func SetTimeoutName(ctx context.Context, name string) {
+	if running, ok := ctx.Value(contextTimeoutKey{}).(*atomic.Pointer[string]); ok {
+		running.Store(&name)
+	}
+}
+
+type RetryPolicy struct {
+	Attempt int
+	Backoff time.Duration
+	Limit   time.Duration
+	Jitter  float64
+	Retry   func(err error) bool
+}
+
+func (it RetryPolicy) retry(err error) bool {
+	if it.Retry != nil {
+		return it.Retry(err)
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+func (it RetryPolicy) delay(attempt int) time.Duration {
+	var d = it.Backoff << (attempt - 1)
+	if d < it.Backoff {
+		d = math.MaxInt64
+	}
+	if it.Limit > 0 && d > it.Limit {
+		d = it.Limit
+	}
+	if it.Jitter > 0 {
+		d -= time.Duration(float64(d) * min(it.Jitter, 1) * rand.Float64())
+	}
+	return d
+}
+
+func Retry(p RetryPolicy, h Handler) Handler {
+	return func(ctx context.Context, target []Node, next Next) (err error) {
+		var called bool
+		var step Next = func(target []Node) error {
+			called = true
+			return next(target)
+		}
+		for attempt := 1; ; attempt++ {
+			var cp = make([]Node, len(target))
+			for i := range target {
+				cp[i] = target[i].Copy()
+			}
+			if err = h(ctx, cp, step); err == nil {
+				copy(target, cp)
+				return nil
+			}
+			if called || attempt >= p.Attempt || !p.retry(err) {
+				if attempt > 1 {
+					return fmt.Errorf("flow: %d attempts: %w", attempt, err)
+				}
+				return err
+			}
+			var t = time.NewTimer(p.delay(attempt))
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return errors.Join(err, ctx.Err())
+			case <-t.C:
+			}
+		}
+	}
+}
+
+func Recover(h Handler) Handler {
+	return func(ctx context.Context, target []Node, next Next) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				var uuid = make([]UUID, 0, len(target))
+				for i := range target {
+					if target[i].UUID.IsSome() {
+						uuid = append(uuid, target[i].UUID.Get())
+					}
+				}
+				err = &PanicError{Value: v, Stack: debug.Stack(), UUID: uuid}
+			}
+		}()
+		return h(ctx, target, next)
+	}
+}
+
+type PanicError struct {
+	Value any
+	Stack []byte
+	UUID  []UUID
+}
+
+func (it *PanicError) Error() string {
+	if len(it.UUID) == 0 {
+		return fmt.Sprintf("flow: panic: %v", it.Value)
+	}
+	var uuid = make([]string, len(it.UUID))
+	for i := range it.UUID {
+		uuid[i] = it.UUID[i].String()
+	}
+	return fmt.Sprintf("flow: panic on %s: %v", strings.Join(uuid, ", "), it.Value)
+}
+func (it *PanicError) Unwrap() error {
+	if err, ok := it.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+type TimeoutError struct {
+	Handler string
+	After   time.Duration
+}
+
+func (it *TimeoutError) Error() string {
+	return fmt.Sprintf("flow: %s timed out after %s", it.Handler, it.After)
+}
+func (it *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
 type Handler func(ctx context.Context, target []Node, next Next) (err error)
 
 func (it Handler) setup(s *Setting) {
@@ -2,7 +2,10 @@ package flow
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/typomaker/option"
@@ -173,6 +176,18 @@ func TestAndCallNextOnEmptyInput(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []int{0}, c)
 }
+func TestAndReuse(t *testing.T) {
+	c := 0
+	f := And(func(ctx context.Context, target []Node, next Next) (err error) {
+		c++
+		return next(target)
+	})
+	for range 2 {
+		err := f(context.Background(), nil, noopNext)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, c)
+}
 
 // func TestNil(t *testing.T) {
 // 	ctx := context.Background()
@@ -275,139 +290,432 @@ func TestAndCallNextOnEmptyInput(t *testing.T) {
 // 	}
 // 	require.Equal(t, e, a)
 // }
-// func TestPipeNext(t *testing.T) {
-// 	ctx := context.Background()
-// 	f := New(
-// 		Pipe{
-// 			Name: option.Some("f1"),
-// 			When: option.Some(When{}),
-// 			Code: option.Some(`
-// 				export default function main(nodes, next){
-// 					nodes[0].meta ??= {seq: []}
-// 					nodes[0].meta.seq.push(this.FLOW_PIPE_NAME)
-// 					next(nodes)
-// 				}
-// 			`),
-// 			Next: option.Some([]Name{"f2", "f3"}),
-// 		},
-// 		Pipe{
-// 			Name: option.Some("f2"),
-// 			Code: option.Some(`
-// 				export default function main(nodes, next){
-// 					nodes[0].meta ??= {seq: []}
-// 					nodes[0].meta.seq.push(this.FLOW_PIPE_NAME)
-// 					next(nodes)
-// 				}
-// 			`),
-// 			Next: option.Some([]Name{"f4", "f2"}),
-// 		},
-// 		Pipe{
-// 			Name: option.Some("f3"),
-// 			Code: option.Some(`
-// 				export default function main(nodes, next){
-// 					nodes[0].meta ??= {seq: []}
-// 					nodes[0].meta.seq.push(this.FLOW_PIPE_NAME)
-// 					next(nodes)
-// 				}
-// 			`),
-// 			Next: option.Some([]Name{"f1"}),
-// 		},
-// 		Pipe{
-// 			Name: option.Some("f4"),
-// 			Next: option.Some([]Name{"f5"}),
-// 		},
-// 		Pipe{
-// 			Name: option.Some("f5"),
-// 			Code: option.Some(`
-// 				export default function main(nodes, next){
-// 					nodes[0].meta ??= {seq: []}
-// 					nodes[0].meta.seq.push(this.FLOW_PIPE_NAME)
-// 					next(nodes)
-// 				}
-// 			`),
-// 		},
-// 	)
-// 	a := []Node{
-// 		{UUID: option.Some(MustUUID("aee6576f-19f8-419c-b3f8-41b770006332"))},
-// 	}
-// 	err := f.Work(ctx, a)
-// 	require.NoError(t, err)
-// 	e := []Node{
-// 		{
-// 			UUID: option.Some(MustUUID("aee6576f-19f8-419c-b3f8-41b770006332")),
-// 			Meta: option.Some(Meta{"seq": []any{
-// 				"f1",
-// 				"f2",
-// 				"f3",
-// 				"f5",
-// 			}}),
-// 		},
-// 	}
-// 	require.Equal(t, e, a)
-// }
-// func TestPipeDedup(t *testing.T) {
-// 	f := New(
-// 		Pipe{
-// 			Name: option.Some[Name]("foo"),
-// 			Code: option.Some("foo"),
-// 		},
-// 		Pipe{
-// 			Name: option.Some[Name]("foo"),
-// 			Code: option.Some("bar"),
-// 		},
-// 		Pipe{
-// 			Name: option.Some("buz"),
-// 			Code: option.Some("buz"),
-// 		},
-// 	)
-// 	require.Len(t, f.stock, 2)
-// 	require.Equal(t, "foo", f.stock[0].Name.Get())
-// 	require.Equal(t, "foo", f.stock[0].Code.Get())
-// 	require.Equal(t, "buz", f.stock[1].Name.Get())
-// 	require.Equal(t, "buz", f.stock[1].Code.Get())
-// }
-// func TestPriority(t *testing.T) {
-// 	f := New(
-// 		Pipe{
-// 			Name: option.Some("f1"),
-// 			Code: option.Some(`export default function main(nodes) {}`),
-// 		},
-// 		Pipe{
-// 			Name: option.Some("f2"),
-// 			Code: option.Some(`export default function main(nodes) {}`),
-// 			Next: option.Some([]string{"f1", "f3"}),
-// 		},
-// 		Pipe{
-// 			Name: option.Some("f3"),
-// 			Code: option.Some(`export default function main(nodes) {}`),
-// 			Next: option.Some([]string{"f1", "f4"}),
-// 		},
-// 		Pipe{
-// 			Name: option.Some("f4"),
-// 			Code: option.Some(`export default function main(nodes) {}`),
-// 			When: option.Some(When{}),
-// 		},
-// 		Pipe{
-// 			Name: option.Some("f5"),
-// 			Code: option.Some(`export default function main(nodes) {}`),
-// 			When: option.Some(When{Hook: option.Some([]Hook{})}),
-// 		},
-// 		Pipe{
-// 			Name: option.Some("f8"),
-// 			Code: option.Some(`export default function main(nodes) {}`),
-// 			When: option.Some(When{UUID: option.Some([]UUID{})}),
-// 		},
-// 		Pipe{
-// 			Name: option.Some("f10"),
-// 			Code: option.Some(`export default function main(nodes) {}`),
-// 			When: option.Some(When{UUID: option.Some([]UUID{}), Hook: option.Some([]Hook{})}),
-// 		},
-// 	)
-// 	require.Equal(t, "f10", f.stock[0].Name.Get())
-// 	require.Equal(t, "f8", f.stock[1].Name.Get())
-// 	require.Equal(t, "f5", f.stock[2].Name.Get())
-// 	require.Equal(t, "f4", f.stock[3].Name.Get())
-// 	require.Equal(t, "f2", f.stock[4].Name.Get())
-// 	require.Equal(t, "f3", f.stock[5].Name.Get())
-// 	require.Equal(t, "f1", f.stock[6].Name.Get())
-// }
+
+func TestParallel(t *testing.T) {
+	cat := MustUUID("10000000-0000-0000-0000-000000000000")
+	dog := MustUUID("20000000-0000-0000-0000-000000000000")
+	wait := make(chan struct{})
+	f := Parallel(
+		Pipe(cat.In(), func(ctx context.Context, target []Node, next Next) (err error) {
+			<-wait
+			for i := range target {
+				target[i].Meta = option.Some(Meta{"kind": "cat"})
+			}
+			return next(target)
+		}),
+		Pipe(dog.In(), func(ctx context.Context, target []Node, next Next) (err error) {
+			close(wait)
+			for i := range target {
+				target[i].Meta = option.Some(Meta{"kind": "dog"})
+			}
+			return next(target)
+		}),
+	)
+	var c int
+	target := []Node{{UUID: option.Some(dog)}, {UUID: option.Some(cat)}, {}}
+	err := f(context.Background(), target, func(target []Node) error {
+		c++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, c)
+	require.Equal(t, Meta{"kind": "dog"}, target[0].Meta.Get())
+	require.Equal(t, Meta{"kind": "cat"}, target[1].Meta.Get())
+	require.True(t, target[2].IsZero())
+}
+func TestParallelN(t *testing.T) {
+	var mu sync.Mutex
+	var run, peak int
+	h := func(ctx context.Context, target []Node, next Next) (err error) {
+		mu.Lock()
+		run++
+		peak = max(peak, run)
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		run--
+		mu.Unlock()
+		return next(target)
+	}
+	err := ParallelN(2, h, h, h, h, h)(context.Background(), nil, noopNext)
+	require.NoError(t, err)
+	require.LessOrEqual(t, peak, 2)
+}
+func TestParallelError(t *testing.T) {
+	a, b := errors.New("a"), errors.New("b")
+	var c int
+	err := Parallel(
+		func(ctx context.Context, target []Node, next Next) (err error) { return a },
+		func(ctx context.Context, target []Node, next Next) (err error) { return next(target) },
+		func(ctx context.Context, target []Node, next Next) (err error) { return b },
+	)(context.Background(), nil, func(target []Node) error {
+		c++
+		return nil
+	})
+	require.ErrorIs(t, err, a)
+	require.ErrorIs(t, err, b)
+	require.Zero(t, c)
+}
+func TestParallelMerge(t *testing.T) {
+	set := func(k string, v any, at ...int) Handler {
+		return func(ctx context.Context, target []Node, next Next) (err error) {
+			for _, i := range at {
+				target[i].Meta = option.Some(Meta{k: v})
+			}
+			return next(target)
+		}
+	}
+	f := Parallel(set("a", 1, 0, 1), set("b", 2, 1), set("c", 3))
+	target := []Node{{}, {}, {Meta: option.Some(Meta{"d": 4})}}
+	require.NoError(t, f(context.Background(), target, noopNext))
+	require.Equal(t, Meta{"a": 1}, target[0].Meta.Get())
+	require.Equal(t, Meta{"b": 2}, target[1].Meta.Get())
+	require.Equal(t, Meta{"d": 4}, target[2].Meta.Get())
+}
+func TestBatch(t *testing.T) {
+	var size []int
+	f := Batch(2, func(ctx context.Context, target []Node, next Next) (err error) {
+		size = append(size, len(target))
+		var cp = make([]Node, len(target))
+		for i := range target {
+			cp[i] = target[i].Copy()
+			cp[i].Meta = option.Some(Meta{"i": i})
+		}
+		return next(cp)
+	})
+	target := []Node{{}, {}, {}, {}, {}}
+	var c int
+	err := f(context.Background(), target, func(v []Node) error {
+		c++
+		require.Equal(t, target, v)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, c)
+	require.Equal(t, []int{2, 2, 1}, size)
+	for i, n := range target {
+		require.Equal(t, Meta{"i": i % 2}, n.Meta.Get())
+	}
+}
+func TestBatchFilter(t *testing.T) {
+	cat := MustUUID("10000000-0000-0000-0000-000000000000")
+	dog := MustUUID("20000000-0000-0000-0000-000000000000")
+	f := BatchN(1, 2, Pipe(cat.In(dog), func(ctx context.Context, target []Node, next Next) (err error) {
+		for i := range target {
+			target[i].Meta = option.Some(Meta{"seen": true})
+		}
+		return next(target)
+	}))
+	target := []Node{{UUID: option.Some(cat)}, {}, {UUID: option.Some(dog)}}
+	var passed []Node
+	err := f(context.Background(), target, func(v []Node) error {
+		passed = v
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, passed, 2)
+	require.Equal(t, cat, passed[0].UUID.Get())
+	require.Equal(t, dog, passed[1].UUID.Get())
+	require.Equal(t, Meta{"seen": true}, target[0].Meta.Get())
+	require.True(t, target[1].IsZero())
+	require.Equal(t, Meta{"seen": true}, target[2].Meta.Get())
+}
+func TestBatchPassthrough(t *testing.T) {
+	f := Batch(2, func(ctx context.Context, target []Node, next Next) (err error) {
+		return next(target)
+	})
+	var passed []Node
+	err := f(context.Background(), []Node{{}, {}}, func(v []Node) error {
+		passed = v
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, passed, 2)
+}
+func TestBatchUUID(t *testing.T) {
+	cat := MustUUID("10000000-0000-0000-0000-000000000000")
+	dog := MustUUID("20000000-0000-0000-0000-000000000000")
+	f := Batch(3, func(ctx context.Context, target []Node, next Next) (err error) {
+		var v = []Node{target[2].Copy(), target[0].Copy()}
+		for i := range v {
+			v[i].Meta = option.Some(Meta{"seen": true})
+		}
+		return next(v)
+	})
+	target := []Node{{UUID: option.Some(cat)}, {}, {UUID: option.Some(dog)}}
+	var passed []Node
+	err := f(context.Background(), target, func(v []Node) error {
+		passed = v
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Node{target[0], target[2]}, passed)
+	require.Equal(t, Meta{"seen": true}, target[0].Meta.Get())
+	require.True(t, target[1].IsZero())
+	require.Equal(t, Meta{"seen": true}, target[2].Meta.Get())
+
+	err = Batch(2, func(ctx context.Context, target []Node, next Next) (err error) {
+		return next([]Node{{}})
+	})(context.Background(), []Node{{UUID: option.Some(cat)}, {}}, noopNext)
+	require.EqualError(t, err, "flow: batch: next got node 0 without a uuid from the chunk")
+}
+func TestBatchError(t *testing.T) {
+	fail := errors.New("fail")
+	var c int
+	err := Batch(1, func(ctx context.Context, target []Node, next Next) (err error) {
+		c++
+		return fail
+	})(context.Background(), []Node{{}, {}}, noopNext)
+	require.ErrorIs(t, err, fail)
+	require.Equal(t, 1, c)
+}
+func TestTimeout(t *testing.T) {
+	slow := func(ctx context.Context, target []Node, next Next) (err error) {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	err := Timeout(time.Millisecond, slow)(context.Background(), nil, noopNext)
+	var timeout *TimeoutError
+	require.ErrorAs(t, err, &timeout)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, time.Millisecond, timeout.After)
+	require.Equal(t, "handler", timeout.Handler)
+
+	err = TimeoutNamed("slow", time.Millisecond, slow)(context.Background(), nil, noopNext)
+	require.ErrorAs(t, err, &timeout)
+	require.Equal(t, "slow", timeout.Handler)
+
+	g, err := NewGraph(GraphPipe{Name: "sleep", When: option.Some(When{}), Handler: Timeout(time.Millisecond, slow)}).Handler()
+	require.NoError(t, err)
+	err = g(context.Background(), []Node{{}}, noopNext)
+	require.ErrorAs(t, err, &timeout)
+	require.Equal(t, "sleep", timeout.Handler)
+
+	fail := errors.New("fail")
+	err = Timeout(time.Second, func(ctx context.Context, target []Node, next Next) (err error) {
+		return fail
+	})(context.Background(), nil, noopNext)
+	require.Equal(t, fail, err)
+
+	var c int
+	err = Timeout(time.Second, func(ctx context.Context, target []Node, next Next) (err error) {
+		return next(target)
+	})(context.Background(), nil, func(target []Node) error {
+		c++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, c)
+}
+func TestTimeoutIgnoreContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	var c int
+	start := time.Now()
+	err := Timeout(10*time.Millisecond, func(ctx context.Context, target []Node, next Next) (err error) {
+		target[0].Meta = option.Some(Meta{"late": true})
+		<-release
+		return next(target)
+	})(context.Background(), []Node{{}}, func(target []Node) error {
+		c++
+		return nil
+	})
+	var timeout *TimeoutError
+	require.ErrorAs(t, err, &timeout)
+	require.Equal(t, "handler", timeout.Handler)
+	require.Less(t, time.Since(start), time.Second)
+	require.Zero(t, c)
+}
+func TestRetry(t *testing.T) {
+	fail := errors.New("fail")
+	var attempt int
+	f := Retry(RetryPolicy{Attempt: 3, Backoff: time.Millisecond, Jitter: 0.5}, func(ctx context.Context, target []Node, next Next) (err error) {
+		attempt++
+		require.Equal(t, Meta{"n": 0}, target[0].Meta.Get())
+		target[0].Meta.Get()["n"] = attempt
+		if attempt < 3 {
+			return fail
+		}
+		return next(target)
+	})
+	target := []Node{{Meta: option.Some(Meta{"n": 0})}}
+	var c int
+	err := f(context.Background(), target, func(target []Node) error {
+		c++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, attempt)
+	require.Equal(t, 1, c)
+	require.Equal(t, Meta{"n": 3}, target[0].Meta.Get())
+}
+func TestRetryExhausted(t *testing.T) {
+	fail := errors.New("fail")
+	var attempt int
+	err := Retry(RetryPolicy{Attempt: 2}, func(ctx context.Context, target []Node, next Next) (err error) {
+		attempt++
+		return fail
+	})(context.Background(), nil, noopNext)
+	require.ErrorIs(t, err, fail)
+	require.EqualError(t, err, "flow: 2 attempts: fail")
+	require.Equal(t, 2, attempt)
+}
+func TestRetryClassify(t *testing.T) {
+	fatal := errors.New("fatal")
+	var attempt int
+	err := Retry(RetryPolicy{
+		Attempt: 5,
+		Retry:   func(err error) bool { return !errors.Is(err, fatal) },
+	}, func(ctx context.Context, target []Node, next Next) (err error) {
+		attempt++
+		return fatal
+	})(context.Background(), nil, noopNext)
+	require.Equal(t, fatal, err)
+	require.Equal(t, 1, attempt)
+
+	attempt = 0
+	fail := errors.New("fail")
+	err = Retry(RetryPolicy{Attempt: 5}, func(ctx context.Context, target []Node, next Next) (err error) {
+		attempt++
+		if err = next(target); err != nil {
+			return err
+		}
+		return nil
+	})(context.Background(), nil, func(target []Node) error { return fail })
+	require.Equal(t, fail, err)
+	require.Equal(t, 1, attempt)
+}
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Backoff: time.Millisecond, Limit: 5 * time.Millisecond}
+	require.Equal(t, time.Millisecond, p.delay(1))
+	require.Equal(t, 2*time.Millisecond, p.delay(2))
+	require.Equal(t, 4*time.Millisecond, p.delay(3))
+	require.Equal(t, 5*time.Millisecond, p.delay(4))
+	require.Equal(t, 5*time.Millisecond, p.delay(100))
+	p.Jitter = 1
+	for range 100 {
+		require.LessOrEqual(t, p.delay(3), 4*time.Millisecond)
+	}
+}
+func TestRecover(t *testing.T) {
+	cat := MustUUID("10000000-0000-0000-0000-000000000000")
+	err := Recover(func(ctx context.Context, target []Node, next Next) (err error) {
+		panic("boom")
+	})(context.Background(), []Node{{UUID: option.Some(cat)}, {}}, noopNext)
+	var p *PanicError
+	require.ErrorAs(t, err, &p)
+	require.Equal(t, "boom", p.Value)
+	require.Equal(t, []UUID{cat}, p.UUID)
+	require.Contains(t, string(p.Stack), "TestRecover")
+	require.EqualError(t, err, "flow: panic on 10000000-0000-0000-0000-000000000000: boom")
+
+	err = Recover(func(ctx context.Context, target []Node, next Next) (err error) {
+		target[0].Copy()
+		return next(target)
+	})(context.Background(), []Node{{Meta: option.Some(Meta{"x": struct{}{}})}}, noopNext)
+	require.ErrorAs(t, err, &p)
+	require.ErrorIs(t, err, ErrUnexpectedType)
+	require.Empty(t, p.UUID)
+
+	err = Recover(func(ctx context.Context, target []Node, next Next) (err error) {
+		return next(target)
+	})(context.Background(), nil, noopNext)
+	require.NoError(t, err)
+}
+func TestPriority(t *testing.T) {
+	name := func(name string) Handler {
+		return func(ctx context.Context, target []Node, next Next) (err error) {
+			for i := range target {
+				target[i].Meta = option.Some(Meta{"name": name})
+			}
+			return next(target)
+		}
+	}
+	g := NewGraph(
+		GraphPipe{Name: "f1", Handler: name("f1")},
+		GraphPipe{Name: "f2", Handler: name("f2"), Next: []string{"f1", "f3"}},
+		GraphPipe{Name: "f3", Handler: name("f3"), Next: []string{"f1", "f4"}},
+		GraphPipe{Name: "f4", Handler: name("f4"), When: option.Some(When{})},
+		GraphPipe{Name: "f5", Handler: name("f5"), When: option.Some(When{Hook: option.Some([]Hook{})})},
+		GraphPipe{Name: "f8", Handler: name("f8"), When: option.Some(When{UUID: option.Some([]UUID{})})},
+		GraphPipe{Name: "f10", Handler: name("f10"), When: option.Some(When{UUID: option.Some([]UUID{}), Hook: option.Some([]Hook{})})},
+	)
+	require.Equal(t, []string{"f10", "f8", "f5", "f4", "f2", "f3", "f1"}, g.Order())
+
+	g.pipe[g.index["f4"]].Priority = option.Some(10)
+	g.pipe[g.index["f10"]].Priority = option.Some(-1)
+	require.Equal(t, []string{"f4", "f8", "f5", "f10", "f2", "f3", "f1"}, g.Order())
+
+	cat := MustUUID("10000000-0000-0000-0000-000000000000")
+	f := Prioritize(
+		Route{When: When{}, Handler: name("rest")},
+		Route{When: When{Hook: option.Some([]Hook{{"kind": "cat"}})}, Handler: name("hook")},
+		Route{When: When{UUID: option.Some([]UUID{cat})}, Handler: name("uuid")},
+		Route{When: When{UUID: option.Some([]UUID{cat}), Hook: option.Some([]Hook{{"kind": "cat"}})}, Handler: name("both")},
+	)
+	target := []Node{
+		{UUID: option.Some(cat), Hook: option.Some(Hook{"kind": "cat"})},
+		{UUID: option.Some(cat), Hook: option.Some(Hook{"kind": "dog"})},
+		{Hook: option.Some(Hook{"kind": "cat"})},
+		{Hook: option.Some(Hook{"kind": "dog"})},
+	}
+	require.NoError(t, f(context.Background(), target, noopNext))
+	for i, e := range []string{"both", "uuid", "hook", "rest"} {
+		require.Equal(t, e, target[i].Meta.Get()["name"])
+	}
+}
+func TestGraph(t *testing.T) {
+	seq := func(ctx context.Context, target []Node, next Next) (err error) {
+		for i := range target {
+			m := target[i].Meta.GetOrZero()
+			if m == nil {
+				m = Meta{}
+			}
+			s, _ := m["seq"].([]any)
+			m["seq"] = append(s, PipeName(ctx))
+			target[i].Meta = option.Some(m)
+		}
+		return next(target)
+	}
+	g := NewGraph(
+		GraphPipe{Name: "f1", When: option.Some(When{}), Handler: seq, Next: []string{"f2", "f3"}},
+		GraphPipe{Name: "f2", Handler: seq, Next: []string{"f4", "f2"}},
+		GraphPipe{Name: "f3", Handler: seq, Next: []string{"f1"}},
+		GraphPipe{Name: "f4", Next: []string{"f5"}},
+		GraphPipe{Name: "f5", Handler: seq},
+	)
+	f, err := g.Handler()
+	require.NoError(t, err)
+	a := []Node{
+		{UUID: option.Some(MustUUID("aee6576f-19f8-419c-b3f8-41b770006332"))},
+	}
+	var passed []Node
+	err = f(context.Background(), a, func(target []Node) error {
+		passed = target
+		return nil
+	})
+	require.NoError(t, err)
+	e := []Node{
+		{
+			UUID: option.Some(MustUUID("aee6576f-19f8-419c-b3f8-41b770006332")),
+			Meta: option.Some(Meta{"seq": []any{"f1", "f2", "f3", "f5"}}),
+		},
+	}
+	require.Equal(t, e, a)
+	require.Equal(t, e, passed)
+
+	g.Add(GraphPipe{Name: "f6", Next: []string{"f7"}})
+	_, err = g.Handler()
+	require.EqualError(t, err, `flow: pipe "f6": unknown next "f7"`)
+}
+func TestGraphDedup(t *testing.T) {
+	g := NewGraph(
+		GraphPipe{Name: "foo", Next: []string{"buz"}},
+		GraphPipe{Name: "foo", Next: []string{"bar"}},
+		GraphPipe{Name: "buz"},
+	)
+	require.Len(t, g.pipe, 2)
+	require.Equal(t, "foo", g.pipe[0].Name)
+	require.Equal(t, []string{"buz"}, g.pipe[0].Next)
+	require.Equal(t, "buz", g.pipe[1].Name)
+}
@@ -3,10 +3,13 @@ package flowtest
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"testing/fstest"
 	"time"
@@ -562,6 +565,7 @@ var jsCases = []struct {
 									},
 									then: {
 										uuid: "08a0cfc4-9dd8-4869-9eec-47ab946e5da3",
+										kind: "qux",
 										meta: {foo: true},
 										hook: {buz: "bar"},
 										live: {
@@ -593,6 +597,10 @@ var jsCases = []struct {
 				},
 				notify.flowCase[0].When.UUID.GetOrZero(),
 			)
+			require.Equal(t,
+				"qux",
+				notify.flowCase[0].Then.Kind.GetOrZero(),
+			)
 			require.Equal(t,
 				flow.Meta{"foo": true},
 				notify.flowCase[0].Then.Meta.GetOrZero(),
@@ -781,6 +789,504 @@ var jsCases = []struct {
 			)
 		},
 	},
+	{
+		name: "interrupt endless loop on timeout",
+		test: func(t *testing.T, provide Provider) {
+			f := flow.New(
+				flow.FS(fstest.MapFS{
+					"path1/index.js": &fstest.MapFile{
+						Data: []byte(`
+							export default function main() {
+								while (true) {}
+							}
+						`),
+					},
+				}),
+				flow.Timeout(10*time.Millisecond, provide(t, "path1/index.js")),
+			)
+			err := f.Run(context.Background(), nil)
+			var timeout *flow.TimeoutError
+			require.ErrorAs(t, err, &timeout)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			require.Equal(t, "path1/index.js", timeout.Handler)
+			require.Equal(t, 10*time.Millisecond, timeout.After)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(10*time.Millisecond, cancel)
+			err = f.Run(ctx, nil)
+			require.Error(t, err)
+		},
+	},
+	{
+		name: "reuse runtime after interrupt",
+		test: func(t *testing.T, provide Provider) {
+			f := flow.New(
+				flow.FS(fstest.MapFS{
+					"path1/index.js": &fstest.MapFile{
+						Data: []byte(`
+							let runs = 0
+							export default function main(nodes, next) {
+								runs++
+								if (nodes.length === 0) {
+									while (true) {}
+								}
+								nodes[0].meta = {runs}
+								next(nodes)
+							}
+						`),
+					},
+				}),
+				flow.Timeout(10*time.Millisecond, provide(t, "path1/index.js")),
+			)
+			err := f.Run(context.Background(), nil)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			for i := range 3 {
+				target := []flow.Node{{}}
+				err = f.Run(context.Background(), target)
+				require.NoError(t, err)
+				if i == 0 {
+					require.Equal(t, flow.Meta{"runs": float64(1)}, target[0].Meta.Get())
+				}
+			}
+		},
+	},
+	{
+		name: "recover modifier panic",
+		test: func(t *testing.T, provide Provider) {
+			f := flow.New(
+				flow.FS(fstest.MapFS{
+					"path1/index.js": &fstest.MapFile{
+						Data: []byte(`
+							export default function main(nodes) {
+								this.modify(nodes[0])
+							}
+						`),
+					},
+				}),
+				flow.Recover(provide(t, "path1/index.js")),
+			)
+			target := []flow.Node{
+				{UUID: option.Some(flow.MustUUID("08a0cfc4-9dd8-4869-9eec-47ab946e5da3"))},
+			}
+			err := f.Run(context.Background(), target, &modifier{panic: "boom"})
+			var p *flow.PanicError
+			require.ErrorAs(t, err, &p)
+			require.Equal(t, "boom", p.Value)
+			require.Equal(t, []flow.UUID{flow.MustUUID("08a0cfc4-9dd8-4869-9eec-47ab946e5da3")}, p.UUID)
+
+			err = f.Run(context.Background(), target, &modifier{})
+			require.NoError(t, err)
+		},
+	},
+	{
+		name: "pipe name in graph",
+		test: func(t *testing.T, provide Provider) {
+			script := []byte(`
+				export default function main(nodes, next) {
+					nodes[0].meta ??= {seq: []}
+					nodes[0].meta.seq.push(this.FLOW_PIPE_NAME)
+					next(nodes)
+				}
+			`)
+			g := flow.NewGraph(
+				flow.GraphPipe{Name: "f1", When: option.Some(flow.When{}), Handler: provide(t, "path1/index.js"), Next: []string{"f2", "f3"}},
+				flow.GraphPipe{Name: "f2", Handler: provide(t, "path1/index.js"), Next: []string{"f4", "f2"}},
+				flow.GraphPipe{Name: "f3", Handler: provide(t, "path1/index.js"), Next: []string{"f1"}},
+				flow.GraphPipe{Name: "f4", Next: []string{"f5"}},
+				flow.GraphPipe{Name: "f5", Handler: provide(t, "path1/index.js")},
+			)
+			h, err := g.Handler()
+			require.NoError(t, err)
+			f := flow.New(
+				flow.FS(fstest.MapFS{
+					"path1/index.js": &fstest.MapFile{Data: script},
+				}),
+				h,
+			)
+			target := []flow.Node{
+				{UUID: option.Some(flow.MustUUID("aee6576f-19f8-419c-b3f8-41b770006332"))},
+			}
+			err = f.Run(context.Background(), target)
+			require.NoError(t, err)
+			require.Equal(t, []any{"f1", "f2", "f3", "f5"}, target[0].Meta.GetOrZero()["seq"])
+		},
+	},
+	{
+		name: "config with script runtime",
+		test: func(t *testing.T, provide Provider) {
+			f := flow.New(
+				flow.FS(fstest.MapFS{
+					"flow.yaml": &fstest.MapFile{
+						Data: []byte(`
+pipe:
+  - hook: [{kind: cat}]
+  - script: path1/index.js
+`),
+					},
+					"path1/index.js": &fstest.MapFile{
+						Data: []byte(`
+							export default function main(nodes) {
+								nodes[0].meta = {seen: true}
+							}
+						`),
+					},
+				}),
+				flow.Config("flow.yaml", flow.Runtime{
+					"script": func(path string) flow.Handler { return provide(t, path) },
+				}),
+			)
+			target := []flow.Node{
+				{Hook: option.Some(flow.Hook{"kind": "cat"})},
+				{Hook: option.Some(flow.Hook{"kind": "dog"})},
+			}
+			for i := range target {
+				err := f.Run(context.Background(), target[i:i+1])
+				require.NoError(t, err)
+			}
+			require.Equal(t, option.Some(flow.Meta{"seen": true}), target[0].Meta)
+			require.False(t, target[1].Meta.IsSome())
+		},
+	},
+	{
+		name: "async main awaits next",
+		test: func(t *testing.T, provide Provider) {
+			f := flow.New(
+				flow.FS(fstest.MapFS{
+					"path1/index.js": &fstest.MapFile{
+						Data: []byte(`
+							export default async function main(nodes, next) {
+								await Promise.resolve()
+								nodes[0].meta.first = true
+								await next(nodes)
+								nodes[0].meta.after = nodes[0].meta.second
+							}
+						`),
+					},
+					"path2/index.js": &fstest.MapFile{
+						Data: []byte(`
+							export default async function main(nodes, next) {
+								nodes[0].meta.second = await Promise.resolve("yes")
+								await next(nodes)
+							}
+						`),
+					},
+				}),
+				flow.Pipe(
+					provide(t, "path1/index.js"),
+					provide(t, "path2/index.js"),
+				),
+			)
+			target := []flow.Node{
+				{Meta: option.Some(flow.Meta{})},
+			}
+			err := f.Run(context.Background(), target)
+			require.NoError(t, err)
+			require.Equal(t,
+				flow.Meta{"first": true, "second": "yes", "after": "yes"},
+				target[0].Meta.GetOrZero(),
+			)
+		},
+	},
+	{
+		name: "async main rejection",
+		test: func(t *testing.T, provide Provider) {
+			boom := errors.New("boom")
+			f := flow.New(
+				flow.FS(fstest.MapFS{
+					"path1/index.js": &fstest.MapFile{
+						Data: []byte(`
+							export default async function main(nodes, next) {
+								if (nodes.length === 0) {
+									await null
+									throw new Error("foo")
+								}
+								await next(nodes)
+							}
+						`),
+					},
+				}),
+				flow.Pipe(
+					provide(t, "path1/index.js"),
+					func(ctx context.Context, target []flow.Node, next flow.Next) error {
+						return boom
+					},
+				),
+			)
+			err := f.Run(context.Background(), nil)
+			require.ErrorContains(t, err, "foo")
+			err = f.Run(context.Background(), []flow.Node{{}})
+			require.ErrorIs(t, err, boom)
+
+			err = flow.New(
+				flow.FS(fstest.MapFS{
+					"path2/index.js": &fstest.MapFile{
+						Data: []byte(`
+							export default function main() {
+								return new Promise(() => {})
+							}
+						`),
+					},
+				}),
+				provide(t, "path2/index.js"),
+			).Run(context.Background(), nil)
+			require.ErrorContains(t, err, "promise never settled")
+		},
+	},
+	{
+		name: "timers",
+		test: func(t *testing.T, provide Provider) {
+			f := flow.New(
+				flow.FS(fstest.MapFS{
+					"path1/index.js": &fstest.MapFile{
+						Data: []byte(`
+							export default function main(nodes, next) {
+								nodes[0].meta.seq = []
+								const push = (v) => nodes[0].meta.seq.push(v)
+								setTimeout(push, 5, "timeout")
+								const never = setTimeout(push, 1, "never")
+								clearTimeout(never)
+								let n = 0
+								const id = setInterval(() => {
+									push("tick")
+									if (++n === 3) {
+										clearInterval(id)
+									}
+								}, 1)
+								next(nodes)
+							}
+						`),
+					},
+					"path2/index.js": &fstest.MapFile{
+						Data: []byte(`
+							export default async function main(nodes) {
+								await new Promise((resolve) => setTimeout(resolve, 1))
+								nodes[0].meta.awaited = true
+							}
+						`),
+					},
+				}),
+				flow.Pipe(
+					provide(t, "path1/index.js"),
+					provide(t, "path2/index.js"),
+				),
+			)
+			target := []flow.Node{
+				{Meta: option.Some(flow.Meta{})},
+			}
+			err := f.Run(context.Background(), target)
+			require.NoError(t, err)
+			require.ElementsMatch(t, []any{"tick", "tick", "tick", "timeout"}, target[0].Meta.GetOrZero()["seq"])
+			require.Equal(t, true, target[0].Meta.GetOrZero()["awaited"])
+		},
+	},
+	{
+		name: "timers cancelled with context",
+		test: func(t *testing.T, provide Provider) {
+			f := flow.New(
+				flow.FS(fstest.MapFS{
+					"path1/index.js": &fstest.MapFile{
+						Data: []byte(`
+							export default function main(nodes) {
+								setInterval(() => {}, 1)
+							}
+						`),
+					},
+				}),
+				flow.Timeout(20*time.Millisecond, provide(t, "path1/index.js")),
+			)
+			err := f.Run(context.Background(), nil)
+			var timeout *flow.TimeoutError
+			require.ErrorAs(t, err, &timeout)
+			require.Equal(t, "path1/index.js", timeout.Handler)
+		},
+	},
+	{
+		name: "fetch",
+		test: func(t *testing.T, provide Provider) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/slow":
+					select {
+					case <-r.Context().Done():
+					case <-time.After(time.Second):
+					}
+				case "/redirect":
+					http.Redirect(w, r, "http://example.com/", http.StatusFound)
+				case "/large":
+					_, _ = w.Write(make([]byte, 64))
+				default:
+					b, _ := io.ReadAll(r.Body)
+					w.Header().Set("X-Method", r.Method)
+					_, _ = fmt.Fprintf(w, `{"token": %q, "body": %q}`, r.Header.Get("X-Token"), b)
+				}
+			}))
+			defer s.Close()
+			u, err := url.Parse(s.URL)
+			require.NoError(t, err)
+			f := flow.New(
+				flow.FS(fstest.MapFS{
+					"path1/index.js": &fstest.MapFile{
+						Data: []byte(`
+							export default async function main(nodes) {
+								const url = nodes[0].meta.url
+								const res = await fetch(new Request(url + "/echo", {
+									method: "post",
+									headers: {"X-Token": "secret"},
+									body: "ping",
+								}))
+								nodes[0].meta.status = res.status
+								nodes[0].meta.ok = res.ok
+								nodes[0].meta.method = res.headers.get("x-method")
+								nodes[0].meta.echo = await res.json()
+								nodes[0].meta.text = await (await fetch(url)).text()
+								for (const path of ["http://example.com/", url + "/redirect", url + "/slow", url + "/large"]) {
+									try {
+										await fetch(path)
+									} catch (e) {
+										nodes[0].meta[path.slice(path.lastIndexOf("/"))] = String(e)
+									}
+								}
+							}
+						`),
+					},
+				}),
+				flow.Fetch{Allow: []string{u.Host}, Timeout: 50 * time.Millisecond, Limit: 48},
+				provide(t, "path1/index.js"),
+			)
+			target := []flow.Node{
+				{Meta: option.Some(flow.Meta{"url": s.URL})},
+			}
+			err = f.Run(context.Background(), target)
+			require.NoError(t, err)
+			meta := target[0].Meta.GetOrZero()
+			require.EqualValues(t, 200, meta["status"])
+			require.Equal(t, true, meta["ok"])
+			require.Equal(t, "POST", meta["method"])
+			require.Equal(t, map[string]any{"token": "secret", "body": "ping"}, meta["echo"])
+			require.Equal(t, `{"token": "", "body": ""}`, meta["text"])
+			require.Contains(t, meta["/"], `host "example.com" is not allowed`)
+			require.Contains(t, meta["/redirect"], `redirect to "example.com" is not allowed`)
+			require.Contains(t, meta["/slow"], "deadline exceeded")
+			require.Contains(t, meta["/large"], "response body exceeds 48 bytes")
+
+			target = []flow.Node{
+				{Meta: option.Some(flow.Meta{"url": s.URL})},
+			}
+			err = f.Run(context.Background(), target)
+			require.NoError(t, err)
+			require.Equal(t, map[string]any{"token": "secret", "body": "ping"}, target[0].Meta.GetOrZero()["echo"])
+		},
+	},
+	{
+		name: "host functions",
+		test: func(t *testing.T, provide Provider) {
+			type key struct{}
+			price := map[string]float64{"a": 10, "b": 20}
+			f := flow.New(
+				flow.FS(fstest.MapFS{
+					"path1/index.js": &fstest.MapFile{
+						Data: []byte(`
+							import {lookup, geo, rate} from "flow:host"
+							export default function main(nodes) {
+								const meta = nodes[0].meta
+								meta.price = lookup(meta.sku) * rate
+								meta.country = geo.country("1.1.1.1")
+								meta.region = geo.region
+								meta.this = this.lookup("b")
+								meta.run = this.run()
+								try {
+									this.fail()
+								} catch (e) {
+									meta.failed = String(e)
+								}
+							}
+						`),
+					},
+				}),
+				flow.Host{
+					"lookup": flow.HostFunc(func(ctx context.Context, args []any) (any, error) {
+						sku, _ := args[0].(string)
+						return price[sku], nil
+					}),
+					"rate": 1.5,
+					"geo": map[string]any{
+						"region": "eu",
+						"country": func(ctx context.Context, args []any) (any, error) {
+							return "AU", nil
+						},
+					},
+				},
+				flow.Host{
+					"run": flow.HostFunc(func(ctx context.Context, args []any) (any, error) {
+						return ctx.Value(key{}), nil
+					}),
+					"fail": flow.HostFunc(func(ctx context.Context, args []any) (any, error) {
+						return nil, errors.New("unavailable")
+					}),
+				},
+				provide(t, "path1/index.js"),
+			)
+			for _, run := range []string{"first", "second"} {
+				target := []flow.Node{
+					{Meta: option.Some(flow.Meta{"sku": "a"})},
+				}
+				err := f.Run(context.WithValue(context.Background(), key{}, run), target)
+				require.NoError(t, err)
+				meta := target[0].Meta.GetOrZero()
+				require.EqualValues(t, 15, meta["price"])
+				require.Equal(t, "AU", meta["country"])
+				require.Equal(t, "eu", meta["region"])
+				require.EqualValues(t, 20, meta["this"])
+				require.Equal(t, run, meta["run"])
+				require.Contains(t, meta["failed"], "unavailable")
+			}
+		},
+	},
+	{
+		name: "host module next to an FS module named host",
+		test: func(t *testing.T, provide Provider) {
+			f := flow.New(
+				flow.FS(fstest.MapFS{
+					"path1/index.js": &fstest.MapFile{
+						Data: []byte(`
+							import {lookup} from "flow:host"
+							import {name} from "flow:./host"
+							export default function main(nodes) {
+								nodes[0].meta = {lookup, name}
+							}
+						`),
+					},
+					"host": &fstest.MapFile{
+						Data: []byte(`export const name = "fs"`),
+					},
+				}),
+				flow.Host{"lookup": "host"},
+				provide(t, "path1/index.js"),
+			)
+			target := []flow.Node{{}}
+			err := f.Run(context.Background(), target)
+			require.NoError(t, err)
+			require.Equal(t, flow.Meta{"lookup": "host", "name": "fs"}, target[0].Meta.Get())
+		},
+	},
+	{
+		name: "host names reserved on this",
+		test: func(t *testing.T, provide Provider) {
+			for _, name := range []string{"modify", "notify"} {
+				f := flow.New(
+					flow.FS(fstest.MapFS{
+						"path1/index.js": &fstest.MapFile{
+							Data: []byte(`export default function main(nodes) {}`),
+						},
+					}),
+					flow.Host{name: 1},
+					provide(t, "path1/index.js"),
+				)
+				err := f.Run(context.Background(), nil)
+				require.ErrorContains(t, err, fmt.Sprintf("host %q is reserved on this", name))
+			}
+		},
+	},
 }
 
 type Provider func(t *testing.T, path string) flow.Handler
@@ -804,11 +1310,15 @@ var slogJsonHandlerOptions = &slog.HandlerOptions{
 type modifier struct {
 	flowNode []flow.Node
 	err      error
+	panic    any
 }
 
 var _ flow.Modifier = (*modifier)(nil)
 
 func (m *modifier) Modify(ctx context.Context, n flow.Node) error {
+	if m.panic != nil {
+		panic(m.panic)
+	}
 	m.flowNode = append(m.flowNode, n)
 	return m.err
 }
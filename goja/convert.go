@@ -3,6 +3,7 @@ package goja
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 	"time"
 
@@ -351,6 +352,20 @@ func convert_GojaValue_FlowThen(rm *goja.Runtime, src goja.Value, dst *flow.Then
 	if !ok {
 		return fmt.Errorf(`must be "Object"`)
 	}
+	if jsKind := jsThen.Get(keyThenKind); jsKind != nil {
+		var goKind flow.Kind
+		switch {
+		case goja.IsUndefined(jsKind):
+			dst.Kind = option.Option[flow.Kind]{}
+		case goja.IsNull(jsKind):
+			dst.Kind = option.None[flow.Kind]()
+		default:
+			if err = rm.ExportTo(jsKind, &goKind); err != nil {
+				return fmt.Errorf(`kind %w`, err)
+			}
+			dst.Kind = option.Some(goKind)
+		}
+	}
 	if jsMeta := jsThen.Get(keyMeta); jsMeta != nil {
 		var goMeta flow.Meta
 		switch {
@@ -489,7 +504,11 @@ func convert_FlowNodeArray_Array(rm *goja.Runtime, src []flow.Node, dst *any) (e
 }
 func convert_FlowNodeArray_LazyNodeArray(rm *goja.Runtime, src []flow.Node, dst **lazyNodeArray) (err error) {
 	var d = *dst
-	d.value = d.value[0:len(src):len(src)]
+	if d.value == nil {
+		d.proto = src
+		return nil
+	}
+	d.value = slices.Grow(d.value[:0], len(src))[:len(src):len(src)]
 	for i, v := range src {
 		if err = convert_FlowNodeObject(rm, v, &d.value[i]); err != nil {
 			return err
@@ -0,0 +1,205 @@
+package goja
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/typomaker/flow"
+)
+
+var fetchProgram = goja.MustCompile("fetch.js", `(function () {
+	let native = null
+	const entries = (init) => {
+		if (init == null) return []
+		if (typeof init[Symbol.iterator] === "function") return init
+		return Object.entries(init)
+	}
+	class Headers {
+		#map = new Map()
+		constructor(init) {
+			for (const [k, v] of entries(init)) this.append(k, v)
+		}
+		append(k, v) {
+			k = String(k).toLowerCase()
+			const p = this.#map.get(k)
+			this.#map.set(k, p === undefined ? String(v) : p + ", " + v)
+		}
+		set(k, v) { this.#map.set(String(k).toLowerCase(), String(v)) }
+		get(k) { return this.#map.get(String(k).toLowerCase()) ?? null }
+		has(k) { return this.#map.has(String(k).toLowerCase()) }
+		delete(k) { this.#map.delete(String(k).toLowerCase()) }
+		forEach(fn, self) { for (const [k, v] of this.#map) fn.call(self, v, k, this) }
+		entries() { return this.#map.entries() }
+		keys() { return this.#map.keys() }
+		values() { return this.#map.values() }
+		[Symbol.iterator]() { return this.#map.entries() }
+	}
+	class Request {
+		constructor(input, init = {}) {
+			const base = input instanceof Request ? input : {url: input, method: "GET", body: null}
+			this.url = String(base.url)
+			this.method = String(init.method ?? base.method).toUpperCase()
+			this.headers = new Headers(init.headers ?? base.headers)
+			this.body = init.body ?? base.body
+		}
+	}
+	class Response {
+		#body
+		constructor(body = null, init = {}) {
+			this.#body = body == null ? "" : String(body)
+			this.status = init.status ?? 200
+			this.statusText = init.statusText ?? ""
+			this.headers = new Headers(init.headers)
+			this.url = init.url ?? ""
+			this.ok = this.status >= 200 && this.status < 300
+			this.bodyUsed = false
+		}
+		async text() {
+			if (this.bodyUsed) throw new TypeError("body already used")
+			this.bodyUsed = true
+			return this.#body
+		}
+		async json() { return JSON.parse(await this.text()) }
+	}
+	function fetch(input, init) {
+		try {
+			const req = new Request(input, init)
+			const body = req.body == null ? null : String(req.body)
+			return native(req.url, req.method, [...req.headers], body).then((r) => new Response(r.body, r))
+		} catch (e) {
+			return Promise.reject(e)
+		}
+	}
+	return {fetch, Request, Response, Headers, bind: (fn) => { native = fn }}
+})`, true)
+
+func importFetch(rm *goja.Runtime) (bind goja.Callable, err error) {
+	var factory goja.Value
+	if factory, err = rm.RunProgram(fetchProgram); err != nil {
+		return nil, err
+	}
+	var call, _ = goja.AssertFunction(factory)
+	var v goja.Value
+	if v, err = call(goja.Undefined()); err != nil {
+		return nil, err
+	}
+	var o = v.ToObject(rm)
+	for _, k := range []string{"fetch", "Request", "Response", "Headers"} {
+		if err = rm.Set(k, o.Get(k)); err != nil {
+			return nil, err
+		}
+	}
+	bind, _ = goja.AssertFunction(o.Get("bind"))
+	return bind, nil
+}
+func bindFetch(ctx context.Context, rm *goja.Runtime, lp *loop, bind goja.Callable) (stop func(), err error) {
+	var setting = flow.Context(ctx).Fetch()
+	var client = setting.HTTPClient()
+	ctx, stop = context.WithCancel(ctx)
+	var native = func(c goja.FunctionCall) goja.Value {
+		var promise, resolve, reject = rm.NewPromise()
+		var req *fetchRequest
+		var err error
+		if req, err = newFetchRequest(ctx, rm, setting, c); err != nil {
+			_ = reject(rm.NewTypeError(err.Error()))
+			return rm.ToValue(promise)
+		}
+		var enqueue = lp.reserve()
+		go func() {
+			var status int
+			var statusText, body string
+			var header http.Header
+			var err = func() (err error) {
+				defer req.cancel()
+				var resp *http.Response
+				if resp, err = client.Do(req.Request); err != nil {
+					return err
+				}
+				defer resp.Body.Close()
+				var b []byte
+				if b, err = io.ReadAll(io.LimitReader(resp.Body, setting.BodyLimit()+1)); err != nil {
+					return err
+				}
+				if int64(len(b)) > setting.BodyLimit() {
+					return fmt.Errorf("response body exceeds %d bytes", setting.BodyLimit())
+				}
+				status, statusText, header, body = resp.StatusCode, http.StatusText(resp.StatusCode), resp.Header, string(b)
+				return nil
+			}()
+			enqueue(func() error {
+				if err != nil {
+					return reject(rm.NewGoError(fmt.Errorf("fetch: %w", err)))
+				}
+				var headers []any
+				for k, vv := range header {
+					for _, v := range vv {
+						headers = append(headers, rm.NewArray(k, v))
+					}
+				}
+				var r = rm.NewObject()
+				for k, v := range map[string]any{
+					"url":        req.URL.String(),
+					"status":     status,
+					"statusText": statusText,
+					"headers":    rm.NewArray(headers...),
+					"body":       body,
+				} {
+					if err := r.Set(k, v); err != nil {
+						return err
+					}
+				}
+				return resolve(r)
+			})
+		}()
+		return rm.ToValue(promise)
+	}
+	if _, err = bind(goja.Undefined(), rm.ToValue(native)); err != nil {
+		stop()
+		return nil, err
+	}
+	return stop, nil
+}
+
+type fetchRequest struct {
+	*http.Request
+	cancel context.CancelFunc
+}
+
+func newFetchRequest(ctx context.Context, rm *goja.Runtime, setting flow.Fetch, c goja.FunctionCall) (_ *fetchRequest, err error) {
+	var u *url.URL
+	if u, err = url.Parse(c.Argument(0).String()); err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+	if !setting.Allowed(u) {
+		return nil, fmt.Errorf("fetch: host %q is not allowed", u.Host)
+	}
+	var body io.Reader
+	if b := c.Argument(3); !goja.IsNull(b) && !goja.IsUndefined(b) {
+		body = strings.NewReader(b.String())
+	}
+	var cancel = context.CancelFunc(func() {})
+	if setting.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, setting.Timeout)
+	}
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, c.Argument(1).String(), u.String(), body); err != nil {
+		cancel()
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+	var headers [][]string
+	if err = rm.ExportTo(c.Argument(2), &headers); err != nil {
+		cancel()
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+	for _, h := range headers {
+		if len(h) == 2 {
+			req.Header.Add(h[0], h[1])
+		}
+	}
+	return &fetchRequest{Request: req, cancel: cancel}, nil
+}
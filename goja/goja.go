@@ -2,9 +2,14 @@ package goja
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/dop251/goja"
 	jsoniter "github.com/json-iterator/go"
@@ -13,69 +18,232 @@ import (
 )
 
 func New(path string) flow.Handler {
-	var po sync.Pool
-	var pm *goja.Program
-	var mu sync.RWMutex
+	return newScript(path, 0).handle
+}
+func Reload(path string, every time.Duration) flow.Handler {
+	return newScript(path, every).handle
+}
 
-	return func(ctx context.Context, target []flow.Node, next flow.Next) (err error) {
-		mu.RLock()
-		var ready = pm != nil
-		mu.RUnlock()
+type script struct {
+	path  string
+	every time.Duration
+	mu    sync.Mutex
+	prog  atomic.Pointer[program]
+	check atomic.Int64
+	stamp map[string]stamp
+}
+type program struct {
+	pm *goja.Program
+	po sync.Pool
+}
+type runtime struct {
+	rm    *goja.Runtime
+	fetch goja.Callable
+}
 
-		if !ready {
-			if mu.TryLock() {
-				var b []byte
-				if b, err = build.Build(ctx, path); err != nil {
-					return fmt.Errorf("goja: %w", err)
-				}
-				if pm, err = goja.Compile("", string(b), true); err != nil {
-					return fmt.Errorf("goja: %w", err)
-				}
-			} else {
-				mu.Lock()
-			}
-			mu.Unlock()
+func newScript(path string, every time.Duration) *script {
+	return &script{path: path, every: every}
+}
+func (it *script) handle(ctx context.Context, target []flow.Node, next flow.Next) (err error) {
+	var pg *program
+	if pg, err = it.load(ctx); err != nil {
+		return err
+	}
+	var path = it.path
+	flow.SetTimeoutName(ctx, path)
+	var rt, _ = pg.po.Get().(*runtime)
+	var pooled = rt != nil
+	if !pooled {
+		rt = &runtime{rm: goja.New()}
+		rt.rm.SetFieldNameMapper(goja.UncapFieldNameMapper())
+		if rt.fetch, err = importFetch(rt.rm); err != nil {
+			return fmt.Errorf("goja: %w", err)
 		}
-		var rm *goja.Runtime
-		if rm, _ = po.Get().(*goja.Runtime); rm == nil {
-			rm = goja.New()
-			rm.SetFieldNameMapper(goja.UncapFieldNameMapper())
-			if err = importConsole(ctx, rm, path); err != nil {
-				return fmt.Errorf("goja: %w", err)
-			}
-			if _, err = rm.RunProgram(pm); err != nil {
-				return fmt.Errorf("goja: %w", err)
-			}
+	}
+	var rm = rt.rm
+	var release = interruptOn(ctx, rm)
+	defer func() {
+		if interrupted := release(); pooled && !interrupted {
+			pg.po.Put(rt)
 		}
-		defer po.Put(rm)
-
-		var jsMain goja.Callable
-		if err = exportMain(ctx, rm, &jsMain); err != nil {
+	}()
+	var lp = newLoop()
+	var stopTimer, stopFetch func()
+	if stopTimer, err = importTimer(ctx, rm, lp); err != nil {
+		return fmt.Errorf("goja: %w", err)
+	}
+	defer stopTimer()
+	if stopFetch, err = bindFetch(ctx, rm, lp, rt.fetch); err != nil {
+		return fmt.Errorf("goja: %w", err)
+	}
+	defer stopFetch()
+	var jsHost *goja.Object
+	if jsHost, err = importHost(ctx, rm); err != nil {
+		return fmt.Errorf("goja: %w", err)
+	}
+	if !pooled {
+		if err = importConsole(ctx, rm, path); err != nil {
 			return fmt.Errorf("goja: %w", err)
 		}
-		var jsTarget goja.Value
-		if err = convert(rm, target, &jsTarget); err != nil {
-			return fmt.Errorf("goja: %w", err)
+		if _, err = rm.RunProgram(pg.pm); err != nil {
+			return interruptError(path, err)
 		}
-		var jsThis = rm.NewObject()
-		if err = importModify(ctx, rm, jsThis); err != nil {
+		pooled = true
+	}
+
+	var jsMain goja.Callable
+	if err = exportMain(ctx, rm, &jsMain); err != nil {
+		return fmt.Errorf("goja: %w", err)
+	}
+	var jsTarget goja.Value
+	if err = convert(rm, target, &jsTarget); err != nil {
+		return fmt.Errorf("goja: %w", err)
+	}
+	var jsThis = rm.NewObject()
+	if name := flow.PipeName(ctx); name != "" {
+		if err = jsThis.Set("FLOW_PIPE_NAME", name); err != nil {
 			return fmt.Errorf("goja: %w", err)
 		}
-		if err = importNotify(ctx, rm, jsThis); err != nil {
-			return fmt.Errorf("goja: %w", err)
+	}
+	if err = assignHost(jsHost, jsThis); err != nil {
+		return fmt.Errorf("goja: %w", err)
+	}
+	if err = importModify(ctx, rm, jsThis); err != nil {
+		return fmt.Errorf("goja: %w", err)
+	}
+	if err = importNotify(ctx, rm, jsThis); err != nil {
+		return fmt.Errorf("goja: %w", err)
+	}
+	var jsNext goja.Value
+	if err = importNext(ctx, rm, next, &jsNext); err != nil {
+		return fmt.Errorf("goja: %w", err)
+	}
+	var jsResult goja.Value
+	if jsResult, err = jsMain(jsThis, jsTarget, jsNext); err != nil {
+		return interruptError(path, err)
+	}
+	if err = awaitValue(ctx, lp, jsResult); err != nil {
+		if ctx.Err() != nil {
+			return causeError(path, context.Cause(ctx))
 		}
-		var jsNext goja.Value
-		if err = importNext(ctx, rm, next, &jsNext); err != nil {
-			return fmt.Errorf("goja: %w", err)
+		return interruptError(path, err)
+	}
+	if err = convert(rm, jsTarget, &target); err != nil {
+		return fmt.Errorf("goja: %w", err)
+	}
+	return nil
+}
+func (it *script) load(ctx context.Context) (pg *program, err error) {
+	if pg = it.prog.Load(); pg != nil && !it.due() {
+		return pg, nil
+	}
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	if pg = it.prog.Load(); pg != nil {
+		if !it.due() {
+			return pg, nil
 		}
-		if _, err = jsMain(jsThis, jsTarget, jsNext); err != nil {
-			return fmt.Errorf("goja: %w", err)
+		it.check.Store(time.Now().Add(it.every).UnixNano())
+		var fsys = flow.Context(ctx).FS()
+		if !it.changed(fsys) {
+			return pg, nil
 		}
-		if err = convert(rm, jsTarget, &target); err != nil {
-			return fmt.Errorf("goja: %w", err)
+		var next *program
+		if next, err = it.compile(ctx); err != nil {
+			flow.Context(ctx).Logger().ErrorContext(ctx, "goja reload", slog.String("path", it.path), slog.String("error", err.Error()))
+			return pg, nil
 		}
-		return nil
+		it.prog.Store(next)
+		return next, nil
+	}
+	if pg, err = it.compile(ctx); err != nil {
+		return nil, err
+	}
+	it.check.Store(time.Now().Add(it.every).UnixNano())
+	it.prog.Store(pg)
+	return pg, nil
+}
+func (it *script) due() bool {
+	return it.every > 0 && time.Now().UnixNano() >= it.check.Load()
+}
+func (it *script) compile(ctx context.Context) (pg *program, err error) {
+	var fsys = flow.Context(ctx).FS()
+	var seen = make(map[string]stamp)
+	var b []byte
+	if b, err = build.Trace(ctx, it.path, func(path string) {
+		if it.every > 0 {
+			seen[path] = stampOf(fsys, path)
+		}
+	}); err != nil {
+		return nil, fmt.Errorf("goja: %w", err)
+	}
+	pg = &program{}
+	if pg.pm, err = goja.Compile("", string(b), true); err != nil {
+		return nil, fmt.Errorf("goja: %w", err)
+	}
+	it.stamp = seen
+	return pg, nil
+}
+func (it *script) changed(fsys fs.FS) bool {
+	for f, s := range it.stamp {
+		if !stampOf(fsys, f).Equal(s) {
+			return true
+		}
+	}
+	return false
+}
+
+type stamp struct {
+	found bool
+	mod   time.Time
+	size  int64
+	sum   [sha256.Size]byte
+}
+
+func stampOf(fsys fs.FS, path string) (v stamp) {
+	var info, err = fs.Stat(fsys, path)
+	if err != nil {
+		return v
+	}
+	v.found, v.mod, v.size = true, info.ModTime(), info.Size()
+	if v.mod.IsZero() {
+		if b, err := fs.ReadFile(fsys, path); err == nil {
+			v.sum = sha256.Sum256(b)
+		}
+	}
+	return v
+}
+func (it stamp) Equal(t stamp) bool {
+	return it.found == t.found && it.mod.Equal(t.mod) && it.size == t.size && it.sum == t.sum
+}
+func interruptOn(ctx context.Context, rm *goja.Runtime) (release func() (interrupted bool)) {
+	var done = make(chan struct{})
+	var stop = context.AfterFunc(ctx, func() {
+		defer close(done)
+		rm.Interrupt(context.Cause(ctx))
+	})
+	return func() (interrupted bool) {
+		if interrupted = !stop(); interrupted {
+			<-done
+		}
+		rm.ClearInterrupt()
+		return interrupted
+	}
+}
+func interruptError(path string, err error) error {
+	var interrupted *goja.InterruptedError
+	if !errors.As(err, &interrupted) {
+		return fmt.Errorf("goja: %w", err)
+	}
+	return causeError(path, err)
+}
+func causeError(path string, err error) error {
+	var timeout *flow.TimeoutError
+	if errors.As(err, &timeout) {
+		return &flow.TimeoutError{Handler: path, After: timeout.After}
 	}
+	return fmt.Errorf("goja: %s: %w", path, err)
 }
 func exportMain(_ context.Context, rm *goja.Runtime, main *goja.Callable) (err error) {
 	var entry goja.Value
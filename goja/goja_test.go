@@ -1,8 +1,13 @@
 package goja
 
 import (
+	"context"
+	"io/fs"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/stretchr/testify/require"
 	"github.com/typomaker/flow"
 	"github.com/typomaker/flow/flowtest"
 )
@@ -12,3 +17,87 @@ func TestJS(t *testing.T) {
 		return New(path)
 	})
 }
+func TestReload(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.js": &fstest.MapFile{Data: []byte(`
+			import value from "flow:lib.js"
+			export default function main(nodes) {
+				nodes[0].meta = {value: value()}
+			}
+		`)},
+		"lib.js": &fstest.MapFile{Data: []byte(`export default function value() { return "a" }`)},
+	}
+	run := func(h flow.Handler) any {
+		target := []flow.Node{{}}
+		err := flow.New(flow.FS(fsys), h).Run(context.Background(), target)
+		require.NoError(t, err)
+		return target[0].Meta.GetOrZero()["value"]
+	}
+	reload, cached := Reload("main.js", time.Nanosecond), New("main.js")
+	require.Equal(t, "a", run(reload))
+	require.Equal(t, "a", run(cached))
+
+	fsys["lib.js"] = &fstest.MapFile{Data: []byte(`export default function value() { return "b" }`)}
+	require.Equal(t, "b", run(reload))
+	require.Equal(t, "a", run(cached))
+
+	fsys["main.js"] = &fstest.MapFile{Data: []byte(`export default function main(nodes) {`)}
+	require.Equal(t, "b", run(reload))
+
+	fsys["main.js"] = &fstest.MapFile{
+		Data:    []byte(`export default function main(nodes) { nodes[0].meta = {value: "c"} }`),
+		ModTime: time.Now(),
+	}
+	require.Equal(t, "c", run(reload))
+
+	fsys["main.js"] = &fstest.MapFile{Data: []byte(`
+		import value from "flow:late.js"
+		export default function main(nodes) {
+			nodes[0].meta = {value: value()}
+		}
+	`)}
+	require.Equal(t, "c", run(reload))
+	require.Equal(t, "c", run(reload))
+	fsys["late.js"] = &fstest.MapFile{Data: []byte(`export default function value() { return "d" }`)}
+	require.Equal(t, "d", run(reload))
+}
+func TestReloadDuringBuild(t *testing.T) {
+	fsys := editFS{MapFS: fstest.MapFS{
+		"main.js": &fstest.MapFile{Data: []byte(`
+			import value from "flow:lib.js"
+			export default function main(nodes) {
+				nodes[0].meta = {value: value()}
+			}
+		`)},
+		"lib.js": &fstest.MapFile{Data: []byte(`export default function value() { return "a" }`)},
+	}}
+	run := func(h flow.Handler) any {
+		target := []flow.Node{{}}
+		err := flow.New(flow.FS(fsys), h).Run(context.Background(), target)
+		require.NoError(t, err)
+		return target[0].Meta.GetOrZero()["value"]
+	}
+	var once bool
+	fsys.open = func(name string) {
+		if name == "lib.js" && !once {
+			once = true
+			fsys.MapFS["lib.js"] = &fstest.MapFile{Data: []byte(`export default function value() { return "b" }`)}
+		}
+	}
+	reload := Reload("main.js", time.Nanosecond)
+	require.Equal(t, "a", run(reload))
+	require.Equal(t, "b", run(reload))
+}
+
+type editFS struct {
+	fstest.MapFS
+	open func(name string)
+}
+
+func (it editFS) Open(name string) (fs.File, error) {
+	var f, err = it.MapFS.Open(name)
+	if it.open != nil {
+		it.open(name)
+	}
+	return f, err
+}
@@ -0,0 +1,85 @@
+package goja
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/dop251/goja"
+	"github.com/typomaker/flow"
+	"github.com/typomaker/flow/build"
+)
+
+func importHost(ctx context.Context, rm *goja.Runtime) (host *goja.Object, err error) {
+	host = rm.NewObject()
+	for name, v := range flow.Context(ctx).Host() {
+		var jsValue goja.Value
+		if jsValue, err = hostValue(ctx, rm, v); err != nil {
+			return nil, fmt.Errorf("host %s: %w", name, err)
+		}
+		if err = host.Set(name, jsValue); err != nil {
+			return nil, err
+		}
+	}
+	if err = rm.GlobalObject().DefineDataProperty(build.HostGlobal, host, goja.FLAG_TRUE, goja.FLAG_TRUE, goja.FLAG_FALSE); err != nil {
+		return nil, err
+	}
+	return host, nil
+}
+
+var reservedThis = []string{"FLOW_PIPE_NAME", "modify", "notify"}
+
+func assignHost(host, this *goja.Object) (err error) {
+	for _, name := range host.Keys() {
+		if slices.Contains(reservedThis, name) {
+			return fmt.Errorf("host %q is reserved on this", name)
+		}
+		if err = this.Set(name, host.Get(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func hostValue(ctx context.Context, rm *goja.Runtime, v any) (jsValue goja.Value, err error) {
+	switch v := v.(type) {
+	case func(context.Context, []any) (any, error):
+		return hostValue(ctx, rm, flow.HostFunc(v))
+	case flow.HostFunc:
+		return rm.ToValue(func(c goja.FunctionCall) goja.Value {
+			var err error
+			var args = make([]any, len(c.Arguments))
+			for i := range c.Arguments {
+				if err = convert(rm, c.Arguments[i], &args[i]); err != nil {
+					err = fmt.Errorf("goja: %w", err)
+					panic(rm.NewGoError(err))
+				}
+			}
+			var result any
+			if result, err = v(ctx, args); err != nil {
+				err = fmt.Errorf("goja: %w", err)
+				panic(rm.NewGoError(err))
+			}
+			var jsResult goja.Value
+			if err = convert(rm, result, &jsResult); err != nil {
+				err = fmt.Errorf("goja: %w", err)
+				panic(rm.NewGoError(err))
+			}
+			return jsResult
+		}), nil
+	case map[string]any:
+		var o = rm.NewObject()
+		for k, val := range v {
+			var jsVal goja.Value
+			if jsVal, err = hostValue(ctx, rm, val); err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			if err = o.Set(k, jsVal); err != nil {
+				return nil, err
+			}
+		}
+		return o, nil
+	default:
+		err = convert(rm, v, &jsValue)
+		return jsValue, err
+	}
+}
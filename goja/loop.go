@@ -0,0 +1,95 @@
+package goja
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+var errStalled = errors.New("promise never settled")
+
+type loop struct {
+	mu      sync.Mutex
+	job     []func() error
+	wake    chan struct{}
+	pending int
+}
+
+func newLoop() *loop {
+	return &loop{wake: make(chan struct{}, 1)}
+}
+func (it *loop) reserve() (enqueue func(job func() error)) {
+	it.mu.Lock()
+	it.pending++
+	it.mu.Unlock()
+
+	var once sync.Once
+	return func(job func() error) {
+		once.Do(func() {
+			it.mu.Lock()
+			it.pending--
+			if job != nil {
+				it.job = append(it.job, job)
+			}
+			it.mu.Unlock()
+			select {
+			case it.wake <- struct{}{}:
+			default:
+			}
+		})
+	}
+}
+func (it *loop) run(ctx context.Context, done func() bool) (err error) {
+	for {
+		it.mu.Lock()
+		var job, pending = it.job, it.pending
+		it.job = nil
+		it.mu.Unlock()
+
+		for _, j := range job {
+			if err = j(); err != nil {
+				return err
+			}
+		}
+		if len(job) != 0 {
+			continue
+		}
+		switch {
+		case done() && pending == 0:
+			return nil
+		case pending == 0:
+			return errStalled
+		}
+		select {
+		case <-it.wake:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+}
+func awaitValue(ctx context.Context, lp *loop, v goja.Value) (err error) {
+	var p, ok = v.Export().(*goja.Promise)
+	if !ok {
+		return lp.run(ctx, func() bool { return true })
+	}
+	if err = lp.run(ctx, func() bool { return p.State() != goja.PromiseStatePending }); err != nil {
+		return err
+	}
+	if p.State() == goja.PromiseStateRejected {
+		return rejectionError(p.Result())
+	}
+	return nil
+}
+func rejectionError(v goja.Value) error {
+	if obj, ok := v.(*goja.Object); ok {
+		if val := obj.Get("value"); val != nil {
+			if err, ok := val.Export().(error); ok {
+				return fmt.Errorf("rejected: %w", err)
+			}
+		}
+	}
+	return fmt.Errorf("rejected: %s", v.String())
+}
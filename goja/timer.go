@@ -0,0 +1,91 @@
+package goja
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+type timer struct {
+	stop    func() bool
+	enqueue func(job func() error)
+}
+
+func importTimer(_ context.Context, rm *goja.Runtime, lp *loop) (stop func(), err error) {
+	var seq int64
+	var active = make(map[int64]*timer)
+
+	var schedule func(id int64, fn goja.Callable, d time.Duration, repeat bool, args []goja.Value)
+	schedule = func(id int64, fn goja.Callable, d time.Duration, repeat bool, args []goja.Value) {
+		var enqueue = lp.reserve()
+		var t = time.AfterFunc(d, func() {
+			enqueue(func() (err error) {
+				if _, ok := active[id]; !ok {
+					return nil
+				}
+				if !repeat {
+					delete(active, id)
+				}
+				if _, err = fn(goja.Undefined(), args...); err != nil {
+					delete(active, id)
+					return err
+				}
+				if _, ok := active[id]; ok {
+					schedule(id, fn, d, repeat, args)
+				}
+				return nil
+			})
+		})
+		active[id] = &timer{stop: t.Stop, enqueue: enqueue}
+	}
+	var set = func(repeat bool) func(c goja.FunctionCall) goja.Value {
+		return func(c goja.FunctionCall) goja.Value {
+			var fn, ok = goja.AssertFunction(c.Argument(0))
+			if !ok {
+				panic(rm.NewTypeError("callback must be a function"))
+			}
+			var ms = c.Argument(1).ToFloat()
+			if !(ms > 0) {
+				ms = 0
+			}
+			var d = time.Duration(ms * float64(time.Millisecond))
+			var args []goja.Value
+			if len(c.Arguments) > 2 {
+				args = slices.Clone(c.Arguments[2:])
+			}
+			seq++
+			schedule(seq, fn, d, repeat, args)
+			return rm.ToValue(seq)
+		}
+	}
+	var cancel = func(id int64) {
+		if t, ok := active[id]; ok {
+			delete(active, id)
+			t.stop()
+			t.enqueue(nil)
+		}
+	}
+	var unset = func(c goja.FunctionCall) goja.Value {
+		cancel(c.Argument(0).ToInteger())
+		return goja.Undefined()
+	}
+	if err = rm.Set("setTimeout", set(false)); err != nil {
+		return nil, err
+	}
+	if err = rm.Set("setInterval", set(true)); err != nil {
+		return nil, err
+	}
+	if err = rm.Set("clearTimeout", unset); err != nil {
+		return nil, err
+	}
+	if err = rm.Set("clearInterval", unset); err != nil {
+		return nil, err
+	}
+	return func() {
+		for id := range active {
+			cancel(id)
+		}
+	}, nil
+}
@@ -17,6 +17,8 @@ const (
 
 	keyCaseWhen = "when"
 	keyCaseThen = "then"
+
+	keyThenKind = "kind"
 )
 
 var (
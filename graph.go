@@ -0,0 +1,132 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/typomaker/option"
+)
+
+type Graph struct {
+	pipe  []GraphPipe
+	index map[string]int
+}
+type GraphPipe struct {
+	Name     string
+	When     option.Option[When]
+	Priority option.Option[int]
+	Handler  Handler
+	Next     []string
+}
+
+func NewGraph(p ...GraphPipe) *Graph {
+	var it = &Graph{index: make(map[string]int)}
+	it.Add(p...)
+	return it
+}
+func (it *Graph) Add(p ...GraphPipe) {
+	for i := range p {
+		if _, ok := it.index[p[i].Name]; ok {
+			continue
+		}
+		it.index[p[i].Name] = len(it.pipe)
+		it.pipe = append(it.pipe, p[i])
+	}
+}
+func (it *Graph) Handler() (_ Handler, err error) {
+	for _, p := range it.pipe {
+		for _, n := range p.Next {
+			if _, ok := it.index[n]; !ok {
+				return nil, fmt.Errorf("flow: pipe %q: unknown next %q", p.Name, n)
+			}
+		}
+	}
+	var r []Route
+	for i, p := range it.pipe {
+		if !p.When.IsSome() {
+			continue
+		}
+		r = append(r, Route{When: p.When.Get(), Priority: p.Priority, Handler: Pipe(it.walk(i)...)})
+	}
+	return Prioritize(r...), nil
+}
+
+// Order lists the pipe names the way the graph runs them: pipes with a When
+// by priority, then the pipes only reached through Next in edge order.
+func (it *Graph) Order() []string {
+	var route, rest []int
+	var indegree = make([]int, len(it.pipe))
+	for i, p := range it.pipe {
+		if p.When.IsSome() {
+			route = append(route, i)
+			continue
+		}
+		for _, n := range p.Next {
+			if j, ok := it.index[n]; ok && !it.pipe[j].When.IsSome() {
+				indegree[j]++
+			}
+		}
+	}
+	slices.SortStableFunc(route, func(a, b int) int {
+		return it.route(b).priority() - it.route(a).priority()
+	})
+	var seen = make([]bool, len(it.pipe))
+	for len(rest) < len(it.pipe)-len(route) {
+		var next = slices.IndexFunc(it.pipe, func(p GraphPipe) bool {
+			var i = it.index[p.Name]
+			return !p.When.IsSome() && !seen[i] && indegree[i] == 0
+		})
+		if next < 0 {
+			next = slices.IndexFunc(it.pipe, func(p GraphPipe) bool {
+				return !p.When.IsSome() && !seen[it.index[p.Name]]
+			})
+		}
+		seen[next] = true
+		rest = append(rest, next)
+		for _, n := range it.pipe[next].Next {
+			if j, ok := it.index[n]; ok {
+				indegree[j]--
+			}
+		}
+	}
+	var v = make([]string, 0, len(it.pipe))
+	for _, i := range append(route, rest...) {
+		v = append(v, it.pipe[i].Name)
+	}
+	return v
+}
+func (it *Graph) route(i int) Route {
+	return Route{When: it.pipe[i].When.GetOrZero(), Priority: it.pipe[i].Priority}
+}
+func (it *Graph) walk(root int) (v []Handler) {
+	var seen = make([]bool, len(it.pipe))
+	var queue = []int{root}
+	seen[root] = true
+	for len(queue) > 0 {
+		var p = it.pipe[queue[0]]
+		queue = queue[1:]
+		if p.Handler != nil {
+			v = append(v, namePipe(p.Name, p.Handler))
+		}
+		for _, n := range p.Next {
+			if j := it.index[n]; !seen[j] {
+				seen[j] = true
+				queue = append(queue, j)
+			}
+		}
+	}
+	return v
+}
+
+type contextPipeKey struct{}
+
+func PipeName(ctx context.Context) string {
+	var name, _ = ctx.Value(contextPipeKey{}).(string)
+	return name
+}
+func namePipe(name string, h Handler) Handler {
+	return func(ctx context.Context, target []Node, next Next) (err error) {
+		return h(context.WithValue(ctx, contextPipeKey{}, name), target, next)
+	}
+}
@@ -11,7 +11,7 @@ import (
 type Hook map[string]any
 
 func (it Hook) Equal(t Hook) bool {
-	return deepEqual(it, t)
+	return deepEqual(map[string]any(it), map[string]any(t))
 }
 func (it Hook) With(pp Hook) Hook {
 	if len(it) == 0 {
@@ -62,3 +62,7 @@ func TestHookLog(t *testing.T) {
 		b.Reset()
 	})
 }
+func TestHookEqual(t *testing.T) {
+	require.True(t, Hook{"a": "1", "b": []any{"2"}}.Equal(Hook{"a": "1", "b": []any{"2"}}))
+	require.False(t, Hook{"a": "1"}.Equal(Hook{"a": "2"}))
+}
@@ -0,0 +1,191 @@
+package keep
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/typomaker/flow"
+	"github.com/typomaker/option"
+)
+
+type Status uint8
+
+const (
+	StatusPending Status = iota
+	StatusConsumed
+)
+
+func (it Status) String() string {
+	switch it {
+	case StatusPending:
+		return "pending"
+	case StatusConsumed:
+		return "consumed"
+	default:
+		return fmt.Sprintf("status(%d)", uint8(it))
+	}
+}
+func (it Status) MarshalText() ([]byte, error) {
+	switch it {
+	case StatusPending, StatusConsumed:
+		return []byte(it.String()), nil
+	default:
+		return nil, fmt.Errorf("unexpected status %d", uint8(it))
+	}
+}
+func (it *Status) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "pending":
+		*it = StatusPending
+	case "consumed":
+		*it = StatusConsumed
+	default:
+		return fmt.Errorf("unexpected status %q", b)
+	}
+	return nil
+}
+
+type Case struct {
+	UUID   flow.UUID `json:"uuid"`
+	Time   time.Time `json:"time"`
+	Status Status    `json:"status"`
+	Case   flow.Case `json:"case"`
+}
+
+type CaseWhen struct {
+	UUID   option.Option[[]flow.UUID]
+	When   option.Option[flow.When]
+	Kind   option.Option[[]flow.Kind]
+	Status option.Option[[]Status]
+}
+
+func (it CaseWhen) match(c Case) bool {
+	switch {
+	case it.UUID.IsSome() && !slices.Contains(it.UUID.Get(), c.UUID):
+		return false
+	case it.When.IsSome() && !it.When.Get().Equal(c.Case.When):
+		return false
+	case it.Kind.IsNone() && c.Case.Then.Kind.IsSome():
+		return false
+	case it.Kind.IsSome() && !c.Case.Then.Kind.IsSome():
+		return false
+	case it.Kind.IsSome() && !slices.Contains(it.Kind.Get(), c.Case.Then.Kind.Get()):
+		return false
+	case it.Status.IsSome() && !slices.Contains(it.Status.Get(), c.Status):
+		return false
+	default:
+		return true
+	}
+}
+
+func (it *Node) Case(v []flow.Case) (err error) {
+	if len(v) == 0 {
+		return nil
+	}
+	var now = time.Now()
+	var c = make([]Case, len(v))
+	for i := range v {
+		c[i] = Case{UUID: flow.NewUUID(), Time: now, Status: StatusPending, Case: v[i]}
+	}
+	if err = it.load().saveCase(c); err != nil {
+		return fmt.Errorf("keep: %w", err)
+	}
+	return nil
+}
+func (it *Node) Cases(w CaseWhen) Cases {
+	return Cases{node: it, when: w}
+}
+func Notifier(s *Node) flow.Notifier {
+	return notifier{node: s}
+}
+
+type notifier struct {
+	node *Node
+}
+
+func (it notifier) Notify(ctx context.Context, c flow.Case) error {
+	if tx := it.node.Tx(ctx); tx != nil {
+		return tx.Case([]flow.Case{c})
+	}
+	return it.node.Case([]flow.Case{c})
+}
+func (it notifier) LogAttr() slog.Attr {
+	return slog.Attr{}
+}
+
+type Cases struct {
+	node *Node
+	when CaseWhen
+}
+
+func (it Cases) Read() CaseRead {
+	return CaseRead{node: it.node, when: it.when, err: new(error)}
+}
+func (it Cases) Consume() (err error) {
+	if err = it.node.load().consumeCase(it.when); err != nil {
+		return fmt.Errorf("keep: %w", err)
+	}
+	return nil
+}
+
+type CaseRead struct {
+	node *Node
+	when CaseWhen
+	err  *error
+}
+
+func (it CaseRead) UUID() iter.Seq[flow.UUID] {
+	return func(yield func(flow.UUID) bool) {
+		for c := range it.Full() {
+			if !yield(c.UUID) {
+				return
+			}
+		}
+	}
+}
+func (it CaseRead) Full() iter.Seq[Case] {
+	return func(yield func(Case) bool) {
+		*it.err = nil
+		for c, err := range it.node.load().readCase(it.when) {
+			if err != nil {
+				*it.err = fmt.Errorf("keep: %w", err)
+				return
+			}
+			if !yield(c) {
+				return
+			}
+		}
+	}
+}
+func (it CaseRead) Err() error {
+	return *it.err
+}
+
+func sortCase(v []Case) {
+	slices.SortFunc(v, func(a, b Case) int {
+		if c := a.Time.Compare(b.Time); c != 0 {
+			return c
+		}
+		return a.UUID.Compare(b.UUID)
+	})
+}
+func copyCase(c Case) Case {
+	if c.Case.When.Hook.IsSome() {
+		var hook = slices.Clone(c.Case.When.Hook.Get())
+		for i := range hook {
+			hook[i] = hook[i].Copy()
+		}
+		c.Case.When.Hook = option.Some(hook)
+	}
+	if c.Case.Then.Meta.IsSome() {
+		c.Case.Then.Meta = option.Some(c.Case.Then.Meta.Get().Copy())
+	}
+	if c.Case.Then.Hook.IsSome() {
+		c.Case.Then.Hook = option.Some(c.Case.Then.Hook.Get().Copy())
+	}
+	return c
+}
@@ -0,0 +1,120 @@
+package keep
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/typomaker/flow"
+	"github.com/typomaker/flow/goja"
+	"github.com/typomaker/option"
+)
+
+func TestCase(t *testing.T) {
+	for name, open := range backend() {
+		t.Run(name, func(t *testing.T) {
+			s := open(t)
+			cat := flow.When{UUID: option.Some([]flow.UUID{flow.MustUUID("10000000-0000-0000-0000-000000000000")})}
+			dog := flow.When{UUID: option.Some([]flow.UUID{flow.MustUUID("20000000-0000-0000-0000-000000000000")})}
+			err := s.Case([]flow.Case{
+				{When: cat, Then: flow.Then{Kind: option.Some("feed"), Meta: option.Some(flow.Meta{"food": "fish"})}},
+				{When: dog, Then: flow.Then{Kind: option.Some("feed")}},
+				{When: dog, Then: flow.Then{Kind: option.Some("walk")}},
+			})
+			require.NoError(t, err)
+
+			r := s.Cases(CaseWhen{Kind: option.Some([]flow.Kind{"feed"})}).Read()
+			feed := slices.Collect(r.Full())
+			require.NoError(t, r.Err())
+			require.Len(t, feed, 2)
+			require.ElementsMatch(t, []flow.When{cat, dog}, []flow.When{feed[0].Case.When, feed[1].Case.When})
+			for _, c := range feed {
+				require.Equal(t, StatusPending, c.Status)
+				require.False(t, c.Time.IsZero())
+				if c.Case.When.Equal(cat) {
+					require.Equal(t, flow.Meta{"food": "fish"}, c.Case.Then.Meta.Get())
+				}
+			}
+
+			r = s.Cases(CaseWhen{When: option.Some(dog)}).Read()
+			require.Len(t, slices.Collect(r.UUID()), 2)
+			require.NoError(t, r.Err())
+
+			err = s.Cases(CaseWhen{When: option.Some(dog), Kind: option.Some([]flow.Kind{"walk"})}).Consume()
+			require.NoError(t, err)
+			pending := slices.Collect(s.Cases(CaseWhen{Status: option.Some([]Status{StatusPending})}).Read().Full())
+			require.Len(t, pending, 2)
+			for _, c := range pending {
+				require.Equal(t, "feed", c.Case.Then.Kind.Get())
+			}
+			consumed := slices.Collect(s.Cases(CaseWhen{Status: option.Some([]Status{StatusConsumed})}).Read().Full())
+			require.Len(t, consumed, 1)
+			require.Equal(t, "walk", consumed[0].Case.Then.Kind.Get())
+		})
+	}
+}
+func TestCaseFileReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFile(dir)
+	require.NoError(t, err)
+	err = s.Case([]flow.Case{
+		{Then: flow.Then{Kind: option.Some("a")}},
+		{Then: flow.Then{Kind: option.Some("b")}},
+	})
+	require.NoError(t, err)
+	err = s.Cases(CaseWhen{Kind: option.Some([]flow.Kind{"a"})}).Consume()
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	s, err = NewFile(dir)
+	require.NoError(t, err)
+	defer s.Close()
+	pending := slices.Collect(s.Cases(CaseWhen{Status: option.Some([]Status{StatusPending})}).Read().Full())
+	require.Len(t, pending, 1)
+	require.Equal(t, "b", pending[0].Case.Then.Kind.Get())
+}
+func TestCaseNotify(t *testing.T) {
+	s := &Node{}
+	f := flow.New(
+		flow.FS(fstest.MapFS{
+			"index.js": &fstest.MapFile{
+				Data: []byte(`
+					export default function main(nodes) {
+						for (const node of nodes) {
+							this.notify({
+								when: {uuid: [node.uuid]},
+								then: {kind: "greet"},
+							})
+						}
+					}
+				`),
+			},
+		}),
+		goja.New("index.js"),
+	)
+	target := []flow.Node{
+		{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))},
+	}
+	err := f.Run(context.Background(), target, Notifier(s))
+	require.NoError(t, err)
+
+	c := slices.Collect(s.Cases(CaseWhen{Kind: option.Some([]flow.Kind{"greet"})}).Read().Full())
+	require.Len(t, c, 1)
+	require.Equal(t,
+		flow.When{UUID: option.Some([]flow.UUID{flow.MustUUID("10000000-0000-0000-0000-000000000000")})},
+		c[0].Case.When,
+	)
+}
+func TestSQLCaseWhen(t *testing.T) {
+	var args []any
+	query, err := sqlCaseWhen(CaseWhen{
+		When:   option.Some(flow.When{UUID: option.Some([]flow.UUID{flow.MustUUID("10000000-0000-0000-0000-000000000000")})}),
+		Kind:   option.Some([]flow.Kind{"feed", "walk"}),
+		Status: option.Some([]Status{StatusPending}),
+	}, &args)
+	require.NoError(t, err)
+	require.Equal(t, `target = $1::jsonb AND kind IN ($2, $3) AND status IN ($4)`, query)
+	require.Equal(t, []any{`{"uuid":["10000000-0000-0000-0000-000000000000"]}`, "feed", "walk", "pending"}, args)
+}
@@ -0,0 +1,120 @@
+package keep
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/typomaker/flow"
+)
+
+const importBatch = 256
+
+type Import uint8
+
+const (
+	ImportUpsert Import = iota
+	ImportCreate
+)
+
+type dumpRecord struct {
+	Node *flow.Node `json:"node,omitempty"`
+	Case *Case      `json:"case,omitempty"`
+}
+
+func (it When) Export(w io.Writer) (err error) {
+	var r = it.Read()
+	for n := range r.Full() {
+		if err = writeDump(w, dumpRecord{Node: &n}); err != nil {
+			return err
+		}
+	}
+	return r.Err()
+}
+func (it Cases) Export(w io.Writer) (err error) {
+	var r = it.Read()
+	for c := range r.Full() {
+		if err = writeDump(w, dumpRecord{Case: &c}); err != nil {
+			return err
+		}
+	}
+	return r.Err()
+}
+
+// Import commits the records in batches of importBatch, each batch on its
+// own. A failing batch is rolled back, the batches before it stay imported.
+func (it *Node) Import(r io.Reader, mode Import) (err error) {
+	var node = make([]flow.Node, 0, importBatch)
+	var kase = make([]Case, 0, importBatch)
+	var flush = func() (err error) {
+		if err = it.commitImport(node, kase, mode); err != nil {
+			return err
+		}
+		node, kase = node[:0], kase[:0]
+		return nil
+	}
+	var br = bufio.NewReader(r)
+	for line := 1; ; line++ {
+		var b []byte
+		if b, err = br.ReadBytes('\n'); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("keep: import: %w", err)
+		}
+		var eof = err != nil
+		if len(bytes.TrimSpace(b)) != 0 {
+			var rec dumpRecord
+			if err = jsoniter.Unmarshal(b, &rec); err != nil {
+				return fmt.Errorf("keep: import line %d: %w", line, err)
+			}
+			switch {
+			case rec.Node != nil:
+				node = append(node, *rec.Node)
+			case rec.Case != nil:
+				kase = append(kase, *rec.Case)
+			default:
+				return fmt.Errorf("keep: import line %d: empty record", line)
+			}
+		}
+		if eof || len(node)+len(kase) >= importBatch {
+			if err = flush(); err != nil {
+				return err
+			}
+		}
+		if eof {
+			return nil
+		}
+	}
+}
+func (it *Node) commitImport(node []flow.Node, kase []Case, mode Import) (err error) {
+	if len(node)+len(kase) == 0 {
+		return nil
+	}
+	if err = checkNode(node); err != nil {
+		return err
+	}
+	for i := range kase {
+		if kase[i].UUID == (flow.UUID{}) {
+			return fmt.Errorf("keep: import: case without uuid")
+		}
+	}
+	sortNode(node)
+	var b = batch{save: node, kase: kase, restore: true, create: mode == ImportCreate}
+	if err = it.load().commit(b); err != nil {
+		return fmt.Errorf("keep: import: %w", err)
+	}
+	return nil
+}
+
+func writeDump(w io.Writer, rec dumpRecord) (err error) {
+	var b []byte
+	if b, err = jsoniter.Marshal(rec); err != nil {
+		return fmt.Errorf("keep: export: %w", err)
+	}
+	b = append(b, '\n')
+	if _, err = w.Write(b); err != nil {
+		return fmt.Errorf("keep: export: %w", err)
+	}
+	return nil
+}
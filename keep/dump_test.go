@@ -0,0 +1,110 @@
+package keep
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/typomaker/flow"
+	"github.com/typomaker/option"
+)
+
+func TestExportImport(t *testing.T) {
+	src := &Node{}
+	err := src.Save([]flow.Node{
+		{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")), Hook: option.Some(flow.Hook{"kind": "cat"}), Meta: option.Some(flow.Meta{"name": "tom"})},
+		{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000")), Hook: option.Some(flow.Hook{"kind": "dog"})},
+	})
+	require.NoError(t, err)
+	err = src.Case([]flow.Case{{Then: flow.Then{Kind: option.Some("feed")}}})
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	require.NoError(t, src.When(flow.When{Hook: option.Some([]flow.Hook{{"kind": "cat"}})}).Export(&b))
+	require.NoError(t, src.Cases(CaseWhen{}).Export(&b))
+	require.Equal(t, 2, strings.Count(b.String(), "\n"))
+
+	dst, err := NewFile(t.TempDir())
+	require.NoError(t, err)
+	defer dst.Close()
+	require.NoError(t, dst.Import(bytes.NewReader(b.Bytes()), ImportCreate))
+
+	node := slicesCollect(dst)
+	require.Len(t, node, 1)
+	require.Equal(t, flow.Meta{"name": "tom"}, node[0].Meta.Get())
+	kase := slices.Collect(dst.Cases(CaseWhen{}).Read().Full())
+	want := slices.Collect(src.Cases(CaseWhen{}).Read().Full())
+	require.Len(t, kase, 1)
+	require.Equal(t, want[0].UUID, kase[0].UUID)
+	require.True(t, want[0].Time.Equal(kase[0].Time))
+	require.Equal(t, "feed", kase[0].Case.Then.Kind.Get())
+
+	err = dst.Import(bytes.NewReader(b.Bytes()), ImportCreate)
+	require.ErrorIs(t, err, ErrConflict)
+	var conflict *ConflictError
+	require.ErrorAs(t, err, &conflict)
+	require.Equal(t, []flow.UUID{flow.MustUUID("10000000-0000-0000-0000-000000000000")}, conflict.UUID)
+
+	require.NoError(t, dst.Import(bytes.NewReader(b.Bytes()), ImportUpsert))
+	require.Len(t, slicesCollect(dst), 1)
+	require.Len(t, slices.Collect(dst.Cases(CaseWhen{}).Read().Full()), 1)
+}
+func TestImportStream(t *testing.T) {
+	var b strings.Builder
+	for i := range importBatch*2 + 1 {
+		fmt.Fprintf(&b, `{"node":{"uuid":"%08x-0000-0000-0000-000000000000","meta":{"i":%d}}}`+"\n\n", i, i)
+	}
+	s := &Node{}
+	require.NoError(t, s.Import(strings.NewReader(b.String()), ImportCreate))
+	require.Len(t, slicesCollect(s), importBatch*2+1)
+
+	late := flow.MustUUID(fmt.Sprintf("%08x-0000-0000-0000-000000000000", importBatch+1))
+	s = &Node{}
+	require.NoError(t, s.Save([]flow.Node{{UUID: option.Some(late)}}))
+	err := s.Import(strings.NewReader(b.String()), ImportCreate)
+	require.ErrorIs(t, err, ErrConflict)
+	var conflict *ConflictError
+	require.ErrorAs(t, err, &conflict)
+	require.Equal(t, []flow.UUID{late}, conflict.UUID)
+	require.Len(t, slicesCollect(s), importBatch+1)
+	require.Empty(t, slices.Collect(s.When(flow.When{UUID: option.Some([]flow.UUID{
+		flow.MustUUID(fmt.Sprintf("%08x-0000-0000-0000-000000000000", importBatch)),
+	})}).Read().UUID()))
+
+	err = s.Import(strings.NewReader(`{"node":{"meta":{}}}`), ImportUpsert)
+	require.ErrorIs(t, err, ErrUUID)
+	err = s.Import(strings.NewReader("{}\n"), ImportUpsert)
+	require.EqualError(t, err, "keep: import line 1: empty record")
+}
+func TestExportImportOrigin(t *testing.T) {
+	src := &Node{}
+	origin := flow.Node{
+		UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")),
+		Hook: option.Some(flow.Hook{"kind": "cat"}),
+	}
+	require.NoError(t, src.Save([]flow.Node{origin}))
+	n := flow.Node{
+		UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")),
+		Hook: option.Some(flow.Hook{"kind": "dog"}),
+	}
+	n.SetOrigin(origin)
+	require.NoError(t, src.Save([]flow.Node{n}))
+
+	var b bytes.Buffer
+	require.NoError(t, src.When(flow.When{}).Export(&b))
+	require.Contains(t, b.String(), `"origin"`)
+
+	dst, err := NewFile(t.TempDir())
+	require.NoError(t, err)
+	defer dst.Close()
+	require.NoError(t, dst.Import(bytes.NewReader(b.Bytes()), ImportCreate))
+	require.NoError(t, dst.Import(bytes.NewReader(b.Bytes()), ImportUpsert))
+
+	node := slicesCollect(dst)
+	require.Len(t, node, 1)
+	require.True(t, n.Equal(node[0]))
+	require.True(t, origin.Equal(node[0].Origin()))
+}
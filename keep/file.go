@@ -0,0 +1,471 @@
+package keep
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/typomaker/flow"
+)
+
+const (
+	fileName       = "node.log"
+	fileCompactMin = 1024
+)
+
+func NewFile(dir string) (_ *Node, err error) {
+	var e *file
+	if e, err = openFile(dir); err != nil {
+		return nil, fmt.Errorf("keep: %w", err)
+	}
+	return &Node{engine: e}, nil
+}
+
+type file struct {
+	mu      sync.RWMutex
+	dir     string
+	fd      *os.File
+	size    int64
+	index   map[flow.UUID]fileSpan
+	kase    map[flow.UUID]fileCase
+	garbage int
+}
+type fileSpan struct {
+	off int64
+	len int64
+}
+type fileCase struct {
+	span   fileSpan
+	time   time.Time
+	status Status
+}
+type fileRecord struct {
+	Save    *flow.Node `json:"save,omitempty"`
+	Drop    *flow.UUID `json:"drop,omitempty"`
+	Case    *Case      `json:"case,omitempty"`
+	Consume *flow.UUID `json:"consume,omitempty"`
+	Commit  int        `json:"commit,omitempty"`
+}
+
+func openFile(dir string) (it *file, err error) {
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	it = newFileState(dir)
+	if it.fd, err = os.OpenFile(filepath.Join(dir, fileName), os.O_RDWR|os.O_CREATE, 0o644); err != nil {
+		return nil, err
+	}
+	if err = it.recover(); err != nil {
+		it.fd.Close()
+		return nil, err
+	}
+	if err = it.compactIf(); err != nil {
+		it.fd.Close()
+		return nil, err
+	}
+	return it, nil
+}
+func newFileState(dir string) *file {
+	return &file{
+		dir:   dir,
+		index: make(map[flow.UUID]fileSpan),
+		kase:  make(map[flow.UUID]fileCase),
+	}
+}
+func (it *file) recover() (err error) {
+	type pending struct {
+		rec  fileRecord
+		span fileSpan
+	}
+	var batch []pending
+	var off, safe int64
+	var r = bufio.NewReader(io.NewSectionReader(it.fd, 0, 1<<62))
+	for {
+		var line []byte
+		if line, err = r.ReadBytes('\n'); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		var rec fileRecord
+		if err = jsoniter.Unmarshal(line, &rec); err != nil {
+			// only the final record may be torn, anything after it is corruption
+			switch _, err = r.Peek(1); {
+			case err == nil:
+				return fmt.Errorf("corrupt record at %d", off)
+			case !errors.Is(err, io.EOF):
+				return err
+			}
+			break
+		}
+		var span = fileSpan{off: off, len: int64(len(line))}
+		off += span.len
+		if rec.Commit == 0 {
+			batch = append(batch, pending{rec: rec, span: span})
+			continue
+		}
+		for _, p := range batch {
+			it.apply(p.rec, p.span)
+		}
+		it.garbage++
+		batch = batch[:0]
+		safe = off
+	}
+	if err = it.fd.Truncate(safe); err != nil {
+		return err
+	}
+	it.size = safe
+	return nil
+}
+func (it *file) apply(rec fileRecord, span fileSpan) {
+	switch {
+	case rec.Save != nil:
+		var u = rec.Save.UUID.Get()
+		if _, ok := it.index[u]; ok {
+			it.garbage++
+		}
+		it.index[u] = span
+	case rec.Drop != nil:
+		if _, ok := it.index[*rec.Drop]; ok {
+			it.garbage++
+		}
+		delete(it.index, *rec.Drop)
+		it.garbage++
+	case rec.Case != nil:
+		it.kase[rec.Case.UUID] = fileCase{span: span, time: rec.Case.Time, status: rec.Case.Status}
+	case rec.Consume != nil:
+		if c, ok := it.kase[*rec.Consume]; ok {
+			c.status = StatusConsumed
+			it.kase[*rec.Consume] = c
+		}
+		it.garbage++
+	}
+}
+func (it *file) record(span fileSpan) (rec fileRecord, err error) {
+	var b = make([]byte, span.len)
+	if _, err = it.fd.ReadAt(b, span.off); err != nil {
+		return rec, err
+	}
+	if err = jsoniter.Unmarshal(b, &rec); err != nil {
+		return rec, err
+	}
+	return rec, nil
+}
+func (it *file) node(span fileSpan) (n flow.Node, err error) {
+	var rec fileRecord
+	if rec, err = it.record(span); err != nil {
+		return n, err
+	}
+	if rec.Save == nil {
+		return n, fmt.Errorf("unexpected record at %d", span.off)
+	}
+	return *rec.Save, nil
+}
+func (it *file) kaseAt(fc fileCase) (c Case, err error) {
+	var rec fileRecord
+	if rec, err = it.record(fc.span); err != nil {
+		return c, err
+	}
+	if rec.Case == nil {
+		return c, fmt.Errorf("unexpected record at %d", fc.span.off)
+	}
+	c = *rec.Case
+	c.Status = fc.status
+	return c, nil
+}
+func (it *file) write(rec []fileRecord) (err error) {
+	if len(rec) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	var span = make([]fileSpan, 0, len(rec))
+	for i := range rec {
+		var b []byte
+		if b, err = jsoniter.Marshal(rec[i]); err != nil {
+			return err
+		}
+		var off = it.size + int64(buf.Len())
+		buf.Write(b)
+		buf.WriteByte('\n')
+		span = append(span, fileSpan{off: off, len: int64(len(b) + 1)})
+	}
+	var b []byte
+	if b, err = jsoniter.Marshal(fileRecord{Commit: len(rec)}); err != nil {
+		return err
+	}
+	buf.Write(b)
+	buf.WriteByte('\n')
+
+	if _, err = it.fd.WriteAt(buf.Bytes(), it.size); err != nil {
+		return errors.Join(err, it.fd.Truncate(it.size))
+	}
+	if err = it.fd.Sync(); err != nil {
+		return errors.Join(err, it.fd.Truncate(it.size))
+	}
+	it.size += int64(buf.Len())
+	for i := range rec {
+		it.apply(rec[i], span[i])
+	}
+	it.garbage++
+	return it.compactIf()
+}
+func (it *file) compactIf() error {
+	if it.garbage < fileCompactMin || it.garbage < len(it.index)+len(it.kase) {
+		return nil
+	}
+	return it.compact()
+}
+func (it *file) compact() (err error) {
+	var name = filepath.Join(it.dir, fileName)
+	var next = newFileState(it.dir)
+	if next.fd, err = os.OpenFile(name+".tmp", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644); err != nil {
+		return err
+	}
+	var rec = make([]fileRecord, 0, len(it.index)+len(it.kase))
+	for _, u := range slices.SortedFunc(maps.Keys(it.index), flow.UUID.Compare) {
+		var n flow.Node
+		if n, err = it.node(it.index[u]); err != nil {
+			next.fd.Close()
+			return err
+		}
+		rec = append(rec, fileRecord{Save: &n})
+	}
+	for _, u := range slices.SortedFunc(maps.Keys(it.kase), flow.UUID.Compare) {
+		var c Case
+		if c, err = it.kaseAt(it.kase[u]); err != nil {
+			next.fd.Close()
+			return err
+		}
+		rec = append(rec, fileRecord{Case: &c})
+	}
+	if err = next.write(rec); err != nil {
+		next.fd.Close()
+		return err
+	}
+	if err = os.Rename(name+".tmp", name); err != nil {
+		next.fd.Close()
+		return err
+	}
+	// the renamed log is the live one now, even if the rename is not durable yet
+	it.fd.Close()
+	it.fd = next.fd
+	it.size = next.size
+	it.index = next.index
+	it.kase = next.kase
+	it.garbage = 0
+	return syncDir(it.dir)
+}
+func (it *file) fill(v []flow.Node) (err error) {
+	it.mu.RLock()
+	defer it.mu.RUnlock()
+
+	for i := range v {
+		if span, ok := it.index[v[i].UUID.Get()]; ok {
+			var n flow.Node
+			if n, err = it.node(span); err != nil {
+				return err
+			}
+			v[i] = filledNode(n)
+		}
+	}
+	return nil
+}
+func (it *file) save(v []flow.Node) (err error) {
+	return it.commit(batch{save: v})
+}
+func (it *file) stored(u flow.UUID) (n flow.Node, ok bool, err error) {
+	var span fileSpan
+	if span, ok = it.index[u]; !ok {
+		return n, false, nil
+	}
+	if n, err = it.node(span); err != nil {
+		return n, false, err
+	}
+	return n, true, nil
+}
+func (it *file) drop(v []flow.Node) (err error) {
+	var uuid = make([]flow.UUID, len(v))
+	for i := range v {
+		uuid[i] = v[i].UUID.Get()
+	}
+	return it.commit(batch{drop: uuid})
+}
+func (it *file) commit(b batch) (err error) {
+	var rec = make([]fileRecord, 0, len(b.save)+len(b.drop)+len(b.kase))
+	for i := range b.save {
+		var n = recordNode(b.save[i])
+		rec = append(rec, fileRecord{Save: &n})
+	}
+	for i := range b.kase {
+		rec = append(rec, fileRecord{Case: &b.kase[i]})
+	}
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	if err = checkOrigin(b.origin(), it.stored); err != nil {
+		return err
+	}
+	if err = checkCreate(b, func(u flow.UUID) bool {
+		var _, ok = it.index[u]
+		return ok
+	}, func(u flow.UUID) bool {
+		var _, ok = it.kase[u]
+		return ok
+	}); err != nil {
+		return err
+	}
+	for i := range b.drop {
+		if _, ok := it.index[b.drop[i]]; ok {
+			rec = append(rec, fileRecord{Drop: &b.drop[i]})
+		}
+	}
+	return it.write(rec)
+}
+func (it *file) dropUUID(uuid []flow.UUID) (err error) {
+	var rec = make([]fileRecord, 0, len(uuid))
+	for i := range uuid {
+		if _, ok := it.index[uuid[i]]; ok {
+			rec = append(rec, fileRecord{Drop: &uuid[i]})
+		}
+	}
+	return it.write(rec)
+}
+func (it *file) read(w flow.When) iter.Seq2[flow.Node, error] {
+	return func(yield func(flow.Node, error) bool) {
+		it.mu.RLock()
+		var uuid = slices.SortedFunc(maps.Keys(it.index), flow.UUID.Compare)
+		it.mu.RUnlock()
+
+		for _, u := range uuid {
+			var n flow.Node
+			var err error
+			it.mu.RLock()
+			var span, ok = it.index[u]
+			if ok {
+				n, err = it.node(span)
+			}
+			it.mu.RUnlock()
+			switch {
+			case err != nil:
+				yield(n, err)
+				return
+			case !ok || !n.When(w):
+				continue
+			}
+			if !yield(n, nil) {
+				return
+			}
+		}
+	}
+}
+func (it *file) dropWhen(w flow.When) (err error) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	var uuid []flow.UUID
+	for u, span := range it.index {
+		var n flow.Node
+		if n, err = it.node(span); err != nil {
+			return err
+		}
+		if n.When(w) {
+			uuid = append(uuid, u)
+		}
+	}
+	slices.SortFunc(uuid, flow.UUID.Compare)
+	return it.dropUUID(uuid)
+}
+func (it *file) saveCase(v []Case) (err error) {
+	return it.commit(batch{kase: v})
+}
+func (it *file) readCase(w CaseWhen) iter.Seq2[Case, error] {
+	return func(yield func(Case, error) bool) {
+		type entry struct {
+			uuid flow.UUID
+			time time.Time
+		}
+		it.mu.RLock()
+		var order = make([]entry, 0, len(it.kase))
+		for u, c := range it.kase {
+			order = append(order, entry{uuid: u, time: c.time})
+		}
+		it.mu.RUnlock()
+		slices.SortFunc(order, func(a, b entry) int {
+			if c := a.time.Compare(b.time); c != 0 {
+				return c
+			}
+			return a.uuid.Compare(b.uuid)
+		})
+
+		for _, e := range order {
+			var c Case
+			var err error
+			it.mu.RLock()
+			var fc, ok = it.kase[e.uuid]
+			if ok {
+				c, err = it.kaseAt(fc)
+			}
+			it.mu.RUnlock()
+			switch {
+			case err != nil:
+				yield(c, err)
+				return
+			case !ok || !w.match(c):
+				continue
+			}
+			if !yield(c, nil) {
+				return
+			}
+		}
+	}
+}
+func (it *file) consumeCase(w CaseWhen) (err error) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	var uuid []flow.UUID
+	for u, fc := range it.kase {
+		if fc.status == StatusConsumed {
+			continue
+		}
+		var c Case
+		if c, err = it.kaseAt(fc); err != nil {
+			return err
+		}
+		if w.match(c) {
+			uuid = append(uuid, u)
+		}
+	}
+	slices.SortFunc(uuid, flow.UUID.Compare)
+	var rec = make([]fileRecord, len(uuid))
+	for i := range uuid {
+		rec[i] = fileRecord{Consume: &uuid[i]}
+	}
+	return it.write(rec)
+}
+func (it *file) Close() error {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	return it.fd.Close()
+}
+
+var syncDir = func(dir string) (err error) {
+	var fd *os.File
+	if fd, err = os.Open(dir); err != nil {
+		return err
+	}
+	defer fd.Close()
+	return fd.Sync()
+}
@@ -0,0 +1,191 @@
+package keep
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/typomaker/flow"
+	"github.com/typomaker/option"
+)
+
+func TestFileReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFile(dir)
+	require.NoError(t, err)
+
+	n := flow.Node{
+		UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")),
+		Meta: option.Some(flow.Meta{"foo": "bar"}),
+		Hook: option.Some(flow.Hook{"kind": "cat"}),
+	}
+	n.SetOrigin(flow.Node{
+		UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")),
+		Hook: option.Some(flow.Hook{"kind": "cat"}),
+	})
+	err = s.Save([]flow.Node{n.Origin()})
+	require.NoError(t, err)
+	err = s.Save([]flow.Node{
+		n,
+		{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))},
+	})
+	require.NoError(t, err)
+	err = s.Drop([]flow.Node{{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))}})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	s, err = NewFile(dir)
+	require.NoError(t, err)
+	defer s.Close()
+
+	target := []flow.Node{
+		{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))},
+		{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))},
+	}
+	err = s.Fill(target)
+	require.NoError(t, err)
+	require.True(t, n.Equal(target[0]))
+	require.True(t, n.Equal(target[0].Origin()))
+	stored := slices.Collect(s.When(flow.When{}).Read().Full())
+	require.Len(t, stored, 1)
+	require.True(t, n.Origin().Equal(stored[0].Origin()))
+	require.Equal(t, flow.Node{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))}, target[1])
+}
+func TestFileRecoverTornTail(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFile(dir)
+	require.NoError(t, err)
+	err = s.Save([]flow.Node{{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))}})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	name := filepath.Join(dir, fileName)
+	info, err := os.Stat(name)
+	require.NoError(t, err)
+	fd, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = fd.WriteString(`{"save":{"uuid":"20000000-0000-0000-0000-000000000000"}}` + "\n" + `{"save":{"uu`)
+	require.NoError(t, err)
+	require.NoError(t, fd.Close())
+
+	s, err = NewFile(dir)
+	require.NoError(t, err)
+	defer s.Close()
+	require.Equal(t,
+		[]flow.UUID{flow.MustUUID("10000000-0000-0000-0000-000000000000")},
+		slices.Collect(s.When(flow.When{}).Read().UUID()),
+	)
+	recovered, err := os.Stat(name)
+	require.NoError(t, err)
+	require.Equal(t, info.Size(), recovered.Size())
+}
+func TestFileRecoverCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFile(dir)
+	require.NoError(t, err)
+	err = s.Save([]flow.Node{{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))}})
+	require.NoError(t, err)
+	err = s.Save([]flow.Node{{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))}})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	name := filepath.Join(dir, fileName)
+	b, err := os.ReadFile(name)
+	require.NoError(t, err)
+	b[bytes.IndexByte(b, '\n')+1] = '#'
+	require.NoError(t, os.WriteFile(name, b, 0o644))
+
+	_, err = NewFile(dir)
+	require.ErrorContains(t, err, "corrupt record")
+	after, err := os.ReadFile(name)
+	require.NoError(t, err)
+	require.Equal(t, b, after)
+}
+func TestFileCompact(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFile(dir)
+	require.NoError(t, err)
+	defer s.Close()
+
+	var size int64
+	for i := range fileCompactMin {
+		err = s.Save([]flow.Node{{
+			UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")),
+			Meta: option.Some(flow.Meta{"i": float64(i)}),
+		}})
+		require.NoError(t, err)
+		if i == 0 {
+			size = s.engine.(*file).size
+		}
+	}
+	info, err := os.Stat(filepath.Join(dir, fileName))
+	require.NoError(t, err)
+	require.Less(t, info.Size(), size*fileCompactMin)
+	require.Less(t, s.engine.(*file).garbage, fileCompactMin)
+
+	target := []flow.Node{{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))}}
+	err = s.Fill(target)
+	require.NoError(t, err)
+	require.Equal(t, flow.Meta{"i": float64(fileCompactMin - 1)}, target[0].Meta.Get())
+}
+func TestFileCompactSyncDir(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFile(dir)
+	require.NoError(t, err)
+	err = s.Save([]flow.Node{{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))}})
+	require.NoError(t, err)
+
+	defer func(f func(string) error) { syncDir = f }(syncDir)
+	syncDir = func(string) error { return errors.New("sync failed") }
+	require.EqualError(t, s.engine.(*file).compact(), "sync failed")
+	syncDir = func(string) error { return nil }
+
+	err = s.Save([]flow.Node{{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))}})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	s, err = NewFile(dir)
+	require.NoError(t, err)
+	defer s.Close()
+	require.Equal(t,
+		[]flow.UUID{
+			flow.MustUUID("10000000-0000-0000-0000-000000000000"),
+			flow.MustUUID("20000000-0000-0000-0000-000000000000"),
+		},
+		slices.Collect(s.When(flow.When{}).Read().UUID()),
+	)
+}
+func TestFileWhen(t *testing.T) {
+	s, err := NewFile(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+
+	err = s.Save([]flow.Node{
+		{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")), Hook: option.Some(flow.Hook{"kind": "cat"})},
+		{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000")), Hook: option.Some(flow.Hook{"kind": "dog"})},
+		{UUID: option.Some(flow.MustUUID("30000000-0000-0000-0000-000000000000")), Hook: option.Some(flow.Hook{"kind": "cat"})},
+	})
+	require.NoError(t, err)
+
+	w := flow.When{Hook: option.Some([]flow.Hook{{"kind": "cat"}})}
+	r := s.When(w).Read()
+	require.Equal(t,
+		[]flow.UUID{
+			flow.MustUUID("10000000-0000-0000-0000-000000000000"),
+			flow.MustUUID("30000000-0000-0000-0000-000000000000"),
+		},
+		slices.Collect(r.UUID()),
+	)
+	require.NoError(t, r.Err())
+
+	err = s.When(w).Drop()
+	require.NoError(t, err)
+	require.Equal(t,
+		[]flow.UUID{flow.MustUUID("20000000-0000-0000-0000-000000000000")},
+		slices.Collect(s.When(flow.When{}).Read().UUID()),
+	)
+}
@@ -0,0 +1,70 @@
+package keep
+
+import (
+	"context"
+
+	"github.com/typomaker/flow"
+	"github.com/typomaker/option"
+)
+
+func Load(s *Node) flow.Handler {
+	return func(ctx context.Context, target []flow.Node, next flow.Next) (err error) {
+		var uuid = make([]flow.UUID, 0, len(target))
+		for i := range target {
+			if target[i].UUID.IsSome() {
+				uuid = append(uuid, target[i].UUID.Get())
+			}
+		}
+		if len(uuid) != 0 {
+			var found = make(map[flow.UUID]flow.Node, len(uuid))
+			var r = s.When(flow.When{UUID: option.Some(uuid)}).Read()
+			for n := range r.Full() {
+				found[n.UUID.Get()] = n
+			}
+			if err = r.Err(); err != nil {
+				return err
+			}
+			if tx := s.Tx(ctx); tx != nil {
+				tx.overlay(uuid, found)
+			}
+			for i := range target {
+				var n, ok = found[target[i].UUID.GetOrZero()]
+				if !ok || !target[i].UUID.IsSome() {
+					continue
+				}
+				target[i].Meta = n.Meta
+				target[i].Hook = n.Hook
+				target[i].Live = n.Live
+				target[i].SetOrigin(bareNode(n))
+			}
+		}
+		return next(target)
+	}
+}
+func Store(s *Node) flow.Handler {
+	return func(ctx context.Context, target []flow.Node, next flow.Next) (err error) {
+		var index = make([]int, 0, len(target))
+		var v = make([]flow.Node, 0, len(target))
+		for i := range target {
+			if target[i].Equal(target[i].Origin()) {
+				continue
+			}
+			index = append(index, i)
+			v = append(v, target[i])
+		}
+		if len(v) != 0 {
+			if tx := s.Tx(ctx); tx != nil {
+				err = tx.Save(v)
+			} else {
+				err = s.Save(v)
+			}
+			if err != nil {
+				return err
+			}
+			for _, i := range index {
+				target[i].SetOrigin(bareNode(target[i]))
+			}
+		}
+		return next(target)
+	}
+}
@@ -0,0 +1,120 @@
+package keep
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/typomaker/flow"
+	"github.com/typomaker/flow/goja"
+	"github.com/typomaker/option"
+)
+
+func TestLoadStore(t *testing.T) {
+	s := &Node{}
+	err := s.Save([]flow.Node{
+		{
+			UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")),
+			Meta: option.Some(flow.Meta{"count": float64(1)}),
+			Hook: option.Some(flow.Hook{"kind": "cat"}),
+		},
+		{
+			UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000")),
+			Meta: option.Some(flow.Meta{"count": float64(1)}),
+			Hook: option.Some(flow.Hook{"kind": "dog"}),
+		},
+	})
+	require.NoError(t, err)
+
+	f := flow.New(
+		flow.FS(fstest.MapFS{
+			"x.js": &fstest.MapFile{
+				Data: []byte(`
+					export default function main(nodes, next) {
+						for (const node of nodes) {
+							if (node.hook.kind === "cat") {
+								node.meta.count++
+							}
+						}
+						next(nodes)
+					}
+				`),
+			},
+		}),
+		flow.Pipe(Load(s), goja.New("x.js"), Store(s)),
+	)
+	target := []flow.Node{
+		{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))},
+		{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))},
+	}
+	err = f.Run(context.Background(), target)
+	require.NoError(t, err)
+	require.Equal(t, flow.Meta{"count": float64(2)}, target[0].Meta.Get())
+
+	stored := []flow.Node{
+		{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))},
+		{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))},
+	}
+	err = s.Fill(stored)
+	require.NoError(t, err)
+	require.Equal(t, flow.Meta{"count": float64(2)}, stored[0].Meta.Get())
+	require.Equal(t, flow.Meta{"count": float64(1)}, stored[1].Meta.Get())
+}
+func TestLoad(t *testing.T) {
+	s := &Node{}
+	stored := flow.Node{
+		UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")),
+		Hook: option.Some(flow.Hook{"kind": "cat"}),
+	}
+	err := s.Save([]flow.Node{stored})
+	require.NoError(t, err)
+
+	target := []flow.Node{
+		{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))},
+		{},
+		{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))},
+	}
+	var reached []flow.Node
+	err = Load(s)(context.Background(), target, func(target []flow.Node) error {
+		reached = target
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, reached, 3)
+	require.True(t, target[0].Origin().IsZero())
+	require.True(t, target[1].IsZero())
+	require.Equal(t, flow.Hook{"kind": "cat"}, target[2].Hook.Get())
+	require.True(t, stored.Equal(target[2].Origin()))
+}
+func TestStoreSkipUnchanged(t *testing.T) {
+	s := &Node{}
+	n := flow.Node{
+		UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")),
+		Hook: option.Some(flow.Hook{"kind": "cat"}),
+	}
+	n.SetOrigin(flow.Node{
+		UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")),
+		Hook: option.Some(flow.Hook{"kind": "cat"}),
+	})
+	err := Store(s)(context.Background(), []flow.Node{n}, func([]flow.Node) error { return nil })
+	require.NoError(t, err)
+	require.Empty(t, slicesCollect(s))
+
+	err = s.Save([]flow.Node{n.Origin()})
+	require.NoError(t, err)
+	n.Hook = option.Some(flow.Hook{"kind": "dog"})
+	target := []flow.Node{n}
+	err = Store(s)(context.Background(), target, func([]flow.Node) error { return nil })
+	require.NoError(t, err)
+	require.Len(t, slicesCollect(s), 1)
+	require.Equal(t, flow.Hook{"kind": "dog"}, slicesCollect(s)[0].Hook.Get())
+	require.Equal(t, flow.Hook{"kind": "dog"}, target[0].Origin().Hook.Get())
+}
+
+func slicesCollect(s *Node) (v []flow.Node) {
+	for n := range s.When(flow.When{}).Read().Full() {
+		v = append(v, n)
+	}
+	return v
+}
@@ -0,0 +1,158 @@
+package keep
+
+import (
+	"iter"
+	"slices"
+	"sync"
+
+	"github.com/typomaker/flow"
+)
+
+type memory struct {
+	hub
+	mu   sync.RWMutex
+	node map[flow.UUID]flow.Node
+	kase map[flow.UUID]Case
+}
+
+func newMemory() *memory {
+	return &memory{
+		node: make(map[flow.UUID]flow.Node),
+		kase: make(map[flow.UUID]Case),
+	}
+}
+func (it *memory) fill(v []flow.Node) error {
+	it.mu.RLock()
+	defer it.mu.RUnlock()
+
+	for i := range v {
+		if n, ok := it.node[v[i].UUID.Get()]; ok {
+			v[i] = filledNode(n)
+		}
+	}
+	return nil
+}
+func (it *memory) save(v []flow.Node) error {
+	return it.commit(batch{save: v})
+}
+func (it *memory) stored(u flow.UUID) (flow.Node, bool, error) {
+	var n, ok = it.node[u]
+	return n, ok, nil
+}
+func (it *memory) drop(v []flow.Node) error {
+	var uuid = make([]flow.UUID, len(v))
+	for i := range v {
+		uuid[i] = v[i].UUID.Get()
+	}
+	return it.commit(batch{drop: uuid})
+}
+func (it *memory) commit(b batch) (err error) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	if err = checkOrigin(b.origin(), it.stored); err != nil {
+		return err
+	}
+	if err = checkCreate(b, func(u flow.UUID) bool {
+		var _, ok = it.node[u]
+		return ok
+	}, func(u flow.UUID) bool {
+		var _, ok = it.kase[u]
+		return ok
+	}); err != nil {
+		return err
+	}
+	var change = make([]Change, 0, len(b.save)+len(b.drop))
+	for i := range b.save {
+		var u = b.save[i].UUID.Get()
+		var n = recordNode(b.save[i])
+		switch before, ok := it.node[u]; {
+		case !ok:
+			change = append(change, Change{Event: EventCreated, After: n})
+		case !before.Equal(n):
+			change = append(change, Change{Event: EventUpdated, Before: before, After: n})
+		}
+		it.node[u] = n
+	}
+	for _, u := range b.drop {
+		if before, ok := it.node[u]; ok {
+			change = append(change, Change{Event: EventDropped, Before: before})
+			delete(it.node, u)
+		}
+	}
+	for i := range b.kase {
+		it.kase[b.kase[i].UUID] = copyCase(b.kase[i])
+	}
+	it.publish(change)
+	return nil
+}
+func (it *memory) read(w flow.When) iter.Seq2[flow.Node, error] {
+	return func(yield func(flow.Node, error) bool) {
+		it.mu.RLock()
+		var v = make([]flow.Node, 0, len(it.node))
+		for _, n := range it.node {
+			if n.When(w) {
+				v = append(v, n.Copy())
+			}
+		}
+		it.mu.RUnlock()
+
+		sortNode(v)
+		for i := range v {
+			if !yield(v[i], nil) {
+				return
+			}
+		}
+	}
+}
+func (it *memory) dropWhen(w flow.When) error {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	var change []Change
+	for u, n := range it.node {
+		if n.When(w) {
+			change = append(change, Change{Event: EventDropped, Before: n})
+			delete(it.node, u)
+		}
+	}
+	slices.SortFunc(change, func(a, b Change) int {
+		return a.Before.UUID.Get().Compare(b.Before.UUID.Get())
+	})
+	it.publish(change)
+	return nil
+}
+func (it *memory) saveCase(v []Case) error {
+	return it.commit(batch{kase: v})
+}
+func (it *memory) readCase(w CaseWhen) iter.Seq2[Case, error] {
+	return func(yield func(Case, error) bool) {
+		it.mu.RLock()
+		var v = make([]Case, 0, len(it.kase))
+		for _, c := range it.kase {
+			if w.match(c) {
+				v = append(v, copyCase(c))
+			}
+		}
+		it.mu.RUnlock()
+
+		sortCase(v)
+		for i := range v {
+			if !yield(v[i], nil) {
+				return
+			}
+		}
+	}
+}
+func (it *memory) consumeCase(w CaseWhen) error {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	for u, c := range it.kase {
+		if w.match(c) {
+			c.Status = StatusConsumed
+			it.kase[u] = c
+		}
+	}
+	return nil
+}
@@ -1,5 +1,58 @@
 package keep
 
-func Migrate() error {
+import (
+	"database/sql"
+	"fmt"
+)
+
+var migration = []string{
+	`CREATE TABLE keep_node (
+		uuid uuid PRIMARY KEY,
+		data jsonb NOT NULL,
+		hook jsonb,
+		live_since timestamptz,
+		live_until timestamptz
+	)`,
+	`CREATE INDEX keep_node_hook ON keep_node USING gin (hook jsonb_path_ops)`,
+	`CREATE INDEX keep_node_live ON keep_node (live_since, live_until)`,
+	`CREATE TABLE keep_case (
+		uuid uuid PRIMARY KEY,
+		time timestamptz NOT NULL,
+		status text NOT NULL,
+		kind text,
+		target jsonb NOT NULL,
+		data jsonb NOT NULL
+	)`,
+	`CREATE INDEX keep_case_kind ON keep_case (kind, status, time)`,
+}
+
+func Migrate(db *sql.DB) (err error) {
+	if _, err = db.Exec(`CREATE TABLE IF NOT EXISTS keep_migration (version integer PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("keep: migrate: %w", err)
+	}
+	var tx *sql.Tx
+	if tx, err = db.Begin(); err != nil {
+		return fmt.Errorf("keep: migrate: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(`LOCK TABLE keep_migration IN EXCLUSIVE MODE`); err != nil {
+		return fmt.Errorf("keep: migrate: %w", err)
+	}
+	var version int
+	if err = tx.QueryRow(`SELECT coalesce(max(version), 0) FROM keep_migration`).Scan(&version); err != nil {
+		return fmt.Errorf("keep: migrate: %w", err)
+	}
+	for ; version < len(migration); version++ {
+		if _, err = tx.Exec(migration[version]); err != nil {
+			return fmt.Errorf("keep: migrate %d: %w", version+1, err)
+		}
+		if _, err = tx.Exec(`INSERT INTO keep_migration (version) VALUES ($1)`, version+1); err != nil {
+			return fmt.Errorf("keep: migrate %d: %w", version+1, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("keep: migrate: %w", err)
+	}
 	return nil
 }
@@ -1,47 +1,222 @@
 package keep
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"iter"
 	"slices"
+	"strings"
+	"sync"
 
 	"github.com/typomaker/flow"
 )
 
-type Node struct{}
+var ErrUUID = errors.New("keep: node without uuid")
+var ErrConflict = errors.New("conflict")
 
-func (it *Node) When(flow.When) When {
-	return When{}
+type ConflictError struct {
+	UUID []flow.UUID
+}
+
+func (it *ConflictError) Error() string {
+	var uuid = make([]string, len(it.UUID))
+	for i := range it.UUID {
+		uuid[i] = it.UUID[i].String()
+	}
+	return "conflict on " + strings.Join(uuid, ", ")
+}
+func (it *ConflictError) Is(err error) bool {
+	return err == ErrConflict
+}
+
+type Node struct {
+	once   sync.Once
+	engine engine
+}
+
+type engine interface {
+	fill(v []flow.Node) error
+	save(v []flow.Node) error
+	drop(v []flow.Node) error
+	read(w flow.When) iter.Seq2[flow.Node, error]
+	dropWhen(w flow.When) error
+	saveCase(v []Case) error
+	readCase(w CaseWhen) iter.Seq2[Case, error]
+	consumeCase(w CaseWhen) error
+	commit(b batch) error
+}
+
+func (it *Node) load() engine {
+	it.once.Do(func() {
+		if it.engine == nil {
+			it.engine = newMemory()
+		}
+	})
+	return it.engine
+}
+func (it *Node) Close() error {
+	if c, ok := it.load().(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+func (it *Node) When(w flow.When) When {
+	return When{node: it, when: w}
 }
 func (it *Node) Fill(v []flow.Node) (err error) {
+	if err = checkNode(v); err != nil {
+		return err
+	}
 	sortNode(v)
 
+	if err = it.load().fill(v); err != nil {
+		return fmt.Errorf("keep: %w", err)
+	}
 	return nil
 }
 func (it *Node) Save(v []flow.Node) (err error) {
+	if err = checkNode(v); err != nil {
+		return err
+	}
 	sortNode(v)
 
+	if err = it.load().save(v); err != nil {
+		return fmt.Errorf("keep: %w", err)
+	}
+	for i := range v {
+		if !v[i].Origin().IsZero() {
+			v[i].SetOrigin(bareNode(v[i]))
+		}
+	}
 	return nil
 }
 func (it *Node) Drop(v []flow.Node) (err error) {
+	if err = checkNode(v); err != nil {
+		return err
+	}
 	sortNode(v)
 
+	if err = it.load().drop(v); err != nil {
+		return fmt.Errorf("keep: %w", err)
+	}
 	return nil
 }
-func (it *Node) Case(v []flow.Case) (err error) {
 
-	return nil
+type When struct {
+	node *Node
+	when flow.When
 }
 
-type When struct{}
-
-func (it When) Read() Read
-func (it When) Drop() (err error)
+func (it When) Read() Read {
+	return Read{node: it.node, when: it.when, err: new(error)}
+}
+func (it When) Drop() (err error) {
+	if err = it.node.load().dropWhen(it.when); err != nil {
+		return fmt.Errorf("keep: %w", err)
+	}
+	return nil
+}
 
-type Read struct{}
+type Read struct {
+	node *Node
+	when flow.When
+	err  *error
+}
 
-func (it Read) UUID() iter.Seq[flow.UUID]
-func (it Read) Full() iter.Seq[flow.Node]
+func (it Read) UUID() iter.Seq[flow.UUID] {
+	return func(yield func(flow.UUID) bool) {
+		for n := range it.Full() {
+			if !yield(n.UUID.Get()) {
+				return
+			}
+		}
+	}
+}
+func (it Read) Full() iter.Seq[flow.Node] {
+	return func(yield func(flow.Node) bool) {
+		*it.err = nil
+		for n, err := range it.node.load().read(it.when) {
+			if err != nil {
+				*it.err = fmt.Errorf("keep: %w", err)
+				return
+			}
+			if !yield(n) {
+				return
+			}
+		}
+	}
+}
+func (it Read) Err() error {
+	return *it.err
+}
 
+func checkNode(v []flow.Node) error {
+	for i := range v {
+		if !v[i].UUID.IsSome() {
+			return ErrUUID
+		}
+	}
+	return nil
+}
+func bareNode(n flow.Node) flow.Node {
+	n = n.Copy()
+	n.SetOrigin(flow.Node{})
+	return n
+}
+func filledNode(n flow.Node) flow.Node {
+	n = bareNode(n)
+	n.SetOrigin(n.Copy())
+	return n
+}
+func recordNode(n flow.Node) flow.Node {
+	n = n.Copy()
+	n.SetOrigin(bareNode(n.Origin()))
+	return n
+}
+func checkOrigin(v []flow.Node, stored func(u flow.UUID) (flow.Node, bool, error)) (err error) {
+	var conflict []flow.UUID
+	for i := range v {
+		var o = v[i].Origin()
+		if o.IsZero() {
+			continue
+		}
+		var n flow.Node
+		var ok bool
+		if n, ok, err = stored(v[i].UUID.Get()); err != nil {
+			return err
+		}
+		if !ok || !bareNode(n).Equal(o) {
+			conflict = append(conflict, v[i].UUID.Get())
+		}
+	}
+	if len(conflict) != 0 {
+		return &ConflictError{UUID: conflict}
+	}
+	return nil
+}
+func checkCreate(b batch, node func(u flow.UUID) bool, kase func(u flow.UUID) bool) error {
+	if !b.create {
+		return nil
+	}
+	var conflict []flow.UUID
+	for i := range b.save {
+		if node(b.save[i].UUID.Get()) {
+			conflict = append(conflict, b.save[i].UUID.Get())
+		}
+	}
+	if len(conflict) == 0 {
+		for i := range b.kase {
+			if kase(b.kase[i].UUID) {
+				conflict = append(conflict, b.kase[i].UUID)
+			}
+		}
+	}
+	if len(conflict) != 0 {
+		return &ConflictError{UUID: conflict}
+	}
+	return nil
+}
 func sortNode(v []flow.Node) {
 	slices.SortFunc(v, func(a, b flow.Node) int {
 		var au = a.UUID.Get()
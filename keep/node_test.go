@@ -0,0 +1,206 @@
+package keep
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/typomaker/flow"
+	"github.com/typomaker/option"
+)
+
+func TestNodeSaveFill(t *testing.T) {
+	s := Node{}
+	err := s.Save([]flow.Node{
+		{
+			UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")),
+			Meta: option.Some(flow.Meta{"foo": "bar"}),
+			Hook: option.Some(flow.Hook{"kind": "cat"}),
+		},
+	})
+	require.NoError(t, err)
+
+	target := []flow.Node{
+		{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))},
+		{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))},
+	}
+	err = s.Fill(target)
+	require.NoError(t, err)
+	want := []flow.Node{
+		{
+			UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")),
+			Meta: option.Some(flow.Meta{"foo": "bar"}),
+			Hook: option.Some(flow.Hook{"kind": "cat"}),
+		},
+		{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))},
+	}
+	want[0].SetOrigin(want[0].Copy())
+	require.Equal(t, want, target)
+
+	target[0].Meta.Get()["foo"] = "buz"
+	err = s.Fill(target[:1])
+	require.NoError(t, err)
+	require.Equal(t, "bar", target[0].Meta.Get()["foo"])
+}
+func TestNodeSaveWithoutUUID(t *testing.T) {
+	s := Node{}
+	err := s.Save([]flow.Node{{Meta: option.Some(flow.Meta{})}})
+	require.ErrorIs(t, err, ErrUUID)
+}
+func TestNodeDrop(t *testing.T) {
+	s := Node{}
+	target := []flow.Node{
+		{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))},
+		{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))},
+	}
+	err := s.Save(target)
+	require.NoError(t, err)
+	err = s.Drop(target[:1])
+	require.NoError(t, err)
+	require.Equal(t,
+		[]flow.UUID{flow.MustUUID("20000000-0000-0000-0000-000000000000")},
+		slices.Collect(s.When(flow.When{}).Read().UUID()),
+	)
+}
+func TestNodeWhen(t *testing.T) {
+	s := Node{}
+	err := s.Save([]flow.Node{
+		{
+			UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")),
+			Hook: option.Some(flow.Hook{"kind": "cat", "tag": []any{"a", "b"}}),
+		},
+		{
+			UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000")),
+			Hook: option.Some(flow.Hook{"kind": "dog"}),
+			Live: option.Some(flow.Live{
+				Since: option.Some(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+				Until: option.Some(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+			}),
+		},
+		{
+			UUID: option.Some(flow.MustUUID("30000000-0000-0000-0000-000000000000")),
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("zero", func(t *testing.T) {
+		require.Equal(t,
+			[]flow.UUID{
+				flow.MustUUID("10000000-0000-0000-0000-000000000000"),
+				flow.MustUUID("20000000-0000-0000-0000-000000000000"),
+				flow.MustUUID("30000000-0000-0000-0000-000000000000"),
+			},
+			slices.Collect(s.When(flow.When{}).Read().UUID()),
+		)
+	})
+	t.Run("uuid", func(t *testing.T) {
+		w := flow.When{UUID: option.Some([]flow.UUID{
+			flow.MustUUID("30000000-0000-0000-0000-000000000000"),
+			flow.MustUUID("40000000-0000-0000-0000-000000000000"),
+		})}
+		require.Equal(t,
+			[]flow.UUID{flow.MustUUID("30000000-0000-0000-0000-000000000000")},
+			slices.Collect(s.When(w).Read().UUID()),
+		)
+	})
+	t.Run("hook", func(t *testing.T) {
+		w := flow.When{Hook: option.Some([]flow.Hook{{"tag": []any{"b"}}, {"kind": "dog"}})}
+		require.Equal(t,
+			[]flow.UUID{
+				flow.MustUUID("10000000-0000-0000-0000-000000000000"),
+				flow.MustUUID("20000000-0000-0000-0000-000000000000"),
+			},
+			slices.Collect(s.When(w).Read().UUID()),
+		)
+	})
+	t.Run("live", func(t *testing.T) {
+		w := flow.When{Live: option.Some([]flow.Live{{
+			Since: option.Some(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+			Until: option.Some(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+		}})}
+		nodes := slices.Collect(s.When(w).Read().Full())
+		require.Len(t, nodes, 1)
+		require.Equal(t, flow.MustUUID("20000000-0000-0000-0000-000000000000"), nodes[0].UUID.Get())
+		require.Equal(t, flow.Hook{"kind": "dog"}, nodes[0].Hook.Get())
+	})
+	t.Run("drop", func(t *testing.T) {
+		w := flow.When{Hook: option.Some([]flow.Hook{{"kind": "cat"}})}
+		err := s.When(w).Drop()
+		require.NoError(t, err)
+		require.Equal(t,
+			[]flow.UUID{
+				flow.MustUUID("20000000-0000-0000-0000-000000000000"),
+				flow.MustUUID("30000000-0000-0000-0000-000000000000"),
+			},
+			slices.Collect(s.When(flow.When{}).Read().UUID()),
+		)
+	})
+}
+func TestNodeConflict(t *testing.T) {
+	for name, open := range backend() {
+		t.Run(name, func(t *testing.T) {
+			s := open(t)
+			err := s.Save([]flow.Node{
+				{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")), Meta: option.Some(flow.Meta{"count": float64(1)})},
+				{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000")), Meta: option.Some(flow.Meta{"count": float64(1)})},
+			})
+			require.NoError(t, err)
+
+			a := []flow.Node{
+				{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))},
+				{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))},
+			}
+			require.NoError(t, s.Fill(a))
+			b := []flow.Node{
+				{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))},
+			}
+			require.NoError(t, s.Fill(b))
+			for i := range a {
+				a[i].SetOrigin(a[i].Copy())
+				a[i].Meta.Get()["count"] = float64(2)
+			}
+			b[0].SetOrigin(b[0].Copy())
+			b[0].Meta.Get()["count"] = float64(3)
+
+			require.NoError(t, s.Save(b))
+			require.Equal(t, float64(3), b[0].Origin().Meta.Get()["count"])
+
+			err = s.Save(a)
+			require.ErrorIs(t, err, ErrConflict)
+			var conflict *ConflictError
+			require.ErrorAs(t, err, &conflict)
+			require.Equal(t, []flow.UUID{flow.MustUUID("10000000-0000-0000-0000-000000000000")}, conflict.UUID)
+
+			stored := []flow.Node{
+				{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))},
+				{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))},
+			}
+			require.NoError(t, s.Fill(stored))
+			require.Equal(t, float64(3), stored[0].Meta.Get()["count"])
+			require.Equal(t, float64(1), stored[1].Meta.Get()["count"])
+
+			b[0].Meta.Get()["count"] = float64(4)
+			require.NoError(t, s.Save(b))
+
+			c := []flow.Node{{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))}}
+			require.NoError(t, s.Fill(c))
+			require.True(t, c[0].Equal(c[0].Origin()))
+			c[0].Meta.Get()["count"] = float64(5)
+			require.NoError(t, s.Save(c))
+			require.NoError(t, s.Fill(stored))
+			require.Equal(t, float64(5), stored[0].Meta.Get()["count"])
+		})
+	}
+}
+func backend() map[string]func(t *testing.T) *Node {
+	return map[string]func(t *testing.T) *Node{
+		"memory": func(t *testing.T) *Node { return &Node{} },
+		"file": func(t *testing.T) *Node {
+			s, err := NewFile(t.TempDir())
+			require.NoError(t, err)
+			t.Cleanup(func() { s.Close() })
+			return s
+		},
+	}
+}
@@ -0,0 +1,479 @@
+package keep
+
+import (
+	"database/sql"
+	"iter"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/typomaker/flow"
+)
+
+func NewSQL(db *sql.DB) *Node {
+	return &Node{engine: &sqlEngine{db: db}}
+}
+
+type sqlEngine struct {
+	db *sql.DB
+}
+
+func (it *sqlEngine) fill(v []flow.Node) (err error) {
+	if len(v) == 0 {
+		return nil
+	}
+	var args = make([]any, 0, len(v))
+	var query = `SELECT data FROM keep_node WHERE uuid IN (` + sqlNodeUUID(v, &args) + `)`
+	var rows *sql.Rows
+	if rows, err = it.db.Query(query, args...); err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var found = make(map[flow.UUID]flow.Node, len(v))
+	for rows.Next() {
+		var n flow.Node
+		if n, err = sqlScanNode(rows); err != nil {
+			return err
+		}
+		found[n.UUID.Get()] = n
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	for i := range v {
+		if n, ok := found[v[i].UUID.Get()]; ok {
+			v[i] = filledNode(n)
+		}
+	}
+	return nil
+}
+func (it *sqlEngine) save(v []flow.Node) (err error) {
+	return it.commit(batch{save: v})
+}
+func (it *sqlEngine) commit(b batch) (err error) {
+	if len(b.save)+len(b.drop)+len(b.kase) == 0 {
+		return nil
+	}
+	var tx *sql.Tx
+	if tx, err = it.db.Begin(); err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = it.checkOrigin(tx, b.origin()); err != nil {
+		return err
+	}
+	if err = sqlSaveNode(tx, b.save, b.create); err != nil {
+		return err
+	}
+	if len(b.drop) != 0 {
+		var args = make([]any, 0, len(b.drop))
+		var in = make([]string, len(b.drop))
+		for i := range b.drop {
+			in[i] = sqlArg(&args, b.drop[i].String())
+		}
+		if _, err = tx.Exec(`DELETE FROM keep_node WHERE `+sqlIn(`uuid`, in), args...); err != nil {
+			return err
+		}
+	}
+	if err = sqlSaveCase(tx, b.kase, b.create); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+func (it *sqlEngine) checkOrigin(tx *sql.Tx, v []flow.Node) (err error) {
+	var origin = make([]flow.Node, 0, len(v))
+	for i := range v {
+		if !v[i].Origin().IsZero() {
+			origin = append(origin, v[i])
+		}
+	}
+	if len(origin) == 0 {
+		return nil
+	}
+	var args = make([]any, 0, len(origin))
+	var query = `SELECT data FROM keep_node WHERE uuid IN (` + sqlNodeUUID(origin, &args) + `) FOR UPDATE`
+	var rows *sql.Rows
+	if rows, err = tx.Query(query, args...); err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var found = make(map[flow.UUID]flow.Node, len(origin))
+	for rows.Next() {
+		var n flow.Node
+		if n, err = sqlScanNode(rows); err != nil {
+			return err
+		}
+		found[n.UUID.Get()] = n
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	return checkOrigin(origin, func(u flow.UUID) (flow.Node, bool, error) {
+		var n, ok = found[u]
+		return n, ok, nil
+	})
+}
+func (it *sqlEngine) drop(v []flow.Node) (err error) {
+	if len(v) == 0 {
+		return nil
+	}
+	var args = make([]any, 0, len(v))
+	var query = `DELETE FROM keep_node WHERE uuid IN (` + sqlNodeUUID(v, &args) + `)`
+	if _, err = it.db.Exec(query, args...); err != nil {
+		return err
+	}
+	return nil
+}
+func (it *sqlEngine) read(w flow.When) iter.Seq2[flow.Node, error] {
+	return func(yield func(flow.Node, error) bool) {
+		var args []any
+		var cond, err = sqlWhen(w, &args)
+		if err != nil {
+			yield(flow.Node{}, err)
+			return
+		}
+		var rows *sql.Rows
+		if rows, err = it.db.Query(`SELECT data FROM keep_node WHERE `+cond+` ORDER BY uuid`, args...); err != nil {
+			yield(flow.Node{}, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var n flow.Node
+			if n, err = sqlScanNode(rows); err != nil {
+				yield(n, err)
+				return
+			}
+			if !yield(n, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(flow.Node{}, err)
+		}
+	}
+}
+func (it *sqlEngine) dropWhen(w flow.When) (err error) {
+	var args []any
+	var cond string
+	if cond, err = sqlWhen(w, &args); err != nil {
+		return err
+	}
+	if _, err = it.db.Exec(`DELETE FROM keep_node WHERE `+cond, args...); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (it *sqlEngine) saveCase(v []Case) (err error) {
+	return it.commit(batch{kase: v})
+}
+func (it *sqlEngine) readCase(w CaseWhen) iter.Seq2[Case, error] {
+	return func(yield func(Case, error) bool) {
+		var args []any
+		var cond, err = sqlCaseWhen(w, &args)
+		if err != nil {
+			yield(Case{}, err)
+			return
+		}
+		var rows *sql.Rows
+		if rows, err = it.db.Query(`SELECT uuid, time, status, data FROM keep_case WHERE `+cond+` ORDER BY time, uuid`, args...); err != nil {
+			yield(Case{}, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var c Case
+			if c, err = sqlScanCase(rows); err != nil {
+				yield(c, err)
+				return
+			}
+			if !yield(c, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(Case{}, err)
+		}
+	}
+}
+func (it *sqlEngine) consumeCase(w CaseWhen) (err error) {
+	var args []any
+	var cond string
+	if cond, err = sqlCaseWhen(w, &args); err != nil {
+		return err
+	}
+	var status = sqlArg(&args, StatusConsumed.String())
+	if _, err = it.db.Exec(`UPDATE keep_case SET status = `+status+` WHERE `+cond, args...); err != nil {
+		return err
+	}
+	return nil
+}
+
+func sqlSaveNode(tx *sql.Tx, v []flow.Node, create bool) (err error) {
+	if len(v) == 0 {
+		return nil
+	}
+	var conflict = `ON CONFLICT (uuid) DO UPDATE SET data = EXCLUDED.data, hook = EXCLUDED.hook, live_since = EXCLUDED.live_since, live_until = EXCLUDED.live_until`
+	if create {
+		conflict = `ON CONFLICT (uuid) DO NOTHING`
+	}
+	var stmt *sql.Stmt
+	if stmt, err = tx.Prepare(`INSERT INTO keep_node (uuid, data, hook, live_since, live_until) VALUES ($1, $2, $3, $4, $5)
+		` + conflict); err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var exist []flow.UUID
+	for i := range v {
+		var args []any
+		if args, err = sqlNodeArgs(v[i]); err != nil {
+			return err
+		}
+		var res sql.Result
+		if res, err = stmt.Exec(args...); err != nil {
+			return err
+		}
+		if !create {
+			continue
+		}
+		var n int64
+		if n, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		if n == 0 {
+			exist = append(exist, v[i].UUID.Get())
+		}
+	}
+	if len(exist) != 0 {
+		return &ConflictError{UUID: exist}
+	}
+	return nil
+}
+func sqlSaveCase(tx *sql.Tx, v []Case, create bool) (err error) {
+	if len(v) == 0 {
+		return nil
+	}
+	var conflict = `ON CONFLICT (uuid) DO UPDATE SET time = EXCLUDED.time, status = EXCLUDED.status, kind = EXCLUDED.kind, target = EXCLUDED.target, data = EXCLUDED.data`
+	if create {
+		conflict = `ON CONFLICT (uuid) DO NOTHING`
+	}
+	var stmt *sql.Stmt
+	if stmt, err = tx.Prepare(`INSERT INTO keep_case (uuid, time, status, kind, target, data) VALUES ($1, $2, $3, $4, $5, $6)
+		` + conflict); err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var exist []flow.UUID
+	for i := range v {
+		var args []any
+		if args, err = sqlCaseArgs(v[i]); err != nil {
+			return err
+		}
+		var res sql.Result
+		if res, err = stmt.Exec(args...); err != nil {
+			return err
+		}
+		if !create {
+			continue
+		}
+		var n int64
+		if n, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		if n == 0 {
+			exist = append(exist, v[i].UUID)
+		}
+	}
+	if len(exist) != 0 {
+		return &ConflictError{UUID: exist}
+	}
+	return nil
+}
+func sqlScanNode(rows *sql.Rows) (n flow.Node, err error) {
+	var data []byte
+	if err = rows.Scan(&data); err != nil {
+		return n, err
+	}
+	if err = jsoniter.Unmarshal(data, &n); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+func sqlNodeArgs(n flow.Node) (args []any, err error) {
+	n = recordNode(n)
+	var data []byte
+	if data, err = jsoniter.Marshal(n); err != nil {
+		return nil, err
+	}
+	var hook, since, until any
+	if n.Hook.IsSome() {
+		var b []byte
+		if b, err = jsoniter.Marshal(n.Hook.Get()); err != nil {
+			return nil, err
+		}
+		hook = string(b)
+	}
+	if n.Live.IsSome() {
+		if l := n.Live.Get(); l.Since.IsSome() {
+			since = l.Since.Get()
+		}
+		if l := n.Live.Get(); l.Until.IsSome() {
+			until = l.Until.Get()
+		}
+	}
+	return []any{n.UUID.Get().String(), string(data), hook, since, until}, nil
+}
+func sqlScanCase(rows *sql.Rows) (c Case, err error) {
+	var uuid, status string
+	var data []byte
+	if err = rows.Scan(&uuid, &c.Time, &status, &data); err != nil {
+		return c, err
+	}
+	if c.UUID, err = flow.ParseUUID(uuid); err != nil {
+		return c, err
+	}
+	if err = c.Status.UnmarshalText([]byte(status)); err != nil {
+		return c, err
+	}
+	if err = jsoniter.Unmarshal(data, &c.Case); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+func sqlCaseArgs(c Case) (args []any, err error) {
+	var target, data []byte
+	if target, err = jsoniter.Marshal(c.Case.When); err != nil {
+		return nil, err
+	}
+	if data, err = jsoniter.Marshal(c.Case); err != nil {
+		return nil, err
+	}
+	var kind any
+	if c.Case.Then.Kind.IsSome() {
+		kind = c.Case.Then.Kind.Get()
+	}
+	return []any{c.UUID.String(), c.Time, c.Status.String(), kind, string(target), string(data)}, nil
+}
+func sqlNodeUUID(v []flow.Node, args *[]any) string {
+	var b strings.Builder
+	for i := range v {
+		if i != 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(sqlArg(args, v[i].UUID.Get().String()))
+	}
+	return b.String()
+}
+func sqlArg(args *[]any, v any) string {
+	*args = append(*args, v)
+	return "$" + strconv.Itoa(len(*args))
+}
+func sqlWhen(w flow.When, args *[]any) (_ string, err error) {
+	var and []string
+	switch {
+	case w.UUID.IsNone():
+		and = append(and, `false`)
+	case w.UUID.IsSome():
+		var in = make([]string, 0, len(w.UUID.Get()))
+		for _, u := range w.UUID.Get() {
+			in = append(in, sqlArg(args, u.String()))
+		}
+		and = append(and, sqlIn(`uuid`, in))
+	}
+	switch {
+	case w.Hook.IsNone():
+		and = append(and, `jsonb_typeof(data->'hook') = 'null'`)
+	case w.Hook.IsSome():
+		var or = make([]string, 0, len(w.Hook.Get()))
+		for _, h := range w.Hook.Get() {
+			var b []byte
+			if b, err = jsoniter.Marshal(map[string]any(h)); err != nil {
+				return "", err
+			}
+			or = append(or, `hook @> `+sqlArg(args, string(b))+`::jsonb`)
+		}
+		and = append(and, `hook IS NOT NULL`, sqlOr(or))
+	}
+	switch {
+	case w.Live.IsNone():
+		and = append(and, `jsonb_typeof(data->'live') = 'null'`)
+	case w.Live.IsSome():
+		var or = make([]string, 0, len(w.Live.Get()))
+		for _, l := range w.Live.Get() {
+			var cond []string
+			if l.Since.IsSome() {
+				cond = append(cond, `(live_since IS NULL OR live_since >= `+sqlArg(args, l.Since.Get())+`)`)
+			}
+			if l.Until.IsSome() {
+				cond = append(cond, `(live_until IS NULL OR live_until <= `+sqlArg(args, l.Until.Get())+`)`)
+			}
+			if len(cond) == 0 {
+				cond = append(cond, `true`)
+			}
+			or = append(or, strings.Join(cond, ` AND `))
+		}
+		and = append(and, `jsonb_typeof(data->'live') = 'object'`, sqlOr(or))
+	}
+	if len(and) == 0 {
+		return `true`, nil
+	}
+	return strings.Join(and, ` AND `), nil
+}
+func sqlCaseWhen(w CaseWhen, args *[]any) (_ string, err error) {
+	var and []string
+	if w.UUID.IsSome() {
+		var in = make([]string, 0, len(w.UUID.Get()))
+		for _, u := range w.UUID.Get() {
+			in = append(in, sqlArg(args, u.String()))
+		}
+		and = append(and, sqlIn(`uuid`, in))
+	}
+	if w.When.IsSome() {
+		var b []byte
+		if b, err = jsoniter.Marshal(w.When.Get()); err != nil {
+			return "", err
+		}
+		and = append(and, `target = `+sqlArg(args, string(b))+`::jsonb`)
+	}
+	switch {
+	case w.Kind.IsNone():
+		and = append(and, `kind IS NULL`)
+	case w.Kind.IsSome():
+		var in = make([]string, 0, len(w.Kind.Get()))
+		for _, k := range w.Kind.Get() {
+			in = append(in, sqlArg(args, k))
+		}
+		and = append(and, sqlIn(`kind`, in))
+	}
+	if w.Status.IsSome() {
+		var in = make([]string, 0, len(w.Status.Get()))
+		for _, s := range w.Status.Get() {
+			in = append(in, sqlArg(args, s.String()))
+		}
+		and = append(and, sqlIn(`status`, in))
+	}
+	if len(and) == 0 {
+		return `true`, nil
+	}
+	return strings.Join(and, ` AND `), nil
+}
+func sqlIn(column string, in []string) string {
+	if len(in) == 0 {
+		return `false`
+	}
+	return column + ` IN (` + strings.Join(in, ", ") + `)`
+}
+func sqlOr(or []string) string {
+	if len(or) == 0 {
+		return `false`
+	}
+	return `(` + strings.Join(or, ` OR `) + `)`
+}
@@ -0,0 +1,369 @@
+package keep
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/typomaker/flow"
+	"github.com/typomaker/option"
+)
+
+func TestMigrate(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.query = func(query string, args []driver.Value) [][]driver.Value {
+		return [][]driver.Value{{int64(0)}}
+	}
+	err := Migrate(db)
+	require.NoError(t, err)
+	expect := []string{
+		"CREATE TABLE IF NOT EXISTS keep_migration (version integer PRIMARY KEY)",
+		"BEGIN",
+		"LOCK TABLE keep_migration IN EXCLUSIVE MODE",
+		"SELECT coalesce(max(version), 0) FROM keep_migration",
+	}
+	for i, m := range migration {
+		m, _, _ = strings.Cut(m, "\n")
+		expect = append(expect, m, fmt.Sprintf("INSERT INTO keep_migration (version) VALUES ($1) [%d]", i+1))
+	}
+	expect = append(expect, "COMMIT")
+	require.Equal(t, expect, fake.trace())
+
+	fake.reset()
+	fake.query = func(query string, args []driver.Value) [][]driver.Value {
+		return [][]driver.Value{{int64(len(migration) - 1)}}
+	}
+	err = Migrate(db)
+	require.NoError(t, err)
+	last, _, _ := strings.Cut(migration[len(migration)-1], "\n")
+	require.Equal(t, []string{
+		"CREATE TABLE IF NOT EXISTS keep_migration (version integer PRIMARY KEY)",
+		"BEGIN",
+		"LOCK TABLE keep_migration IN EXCLUSIVE MODE",
+		"SELECT coalesce(max(version), 0) FROM keep_migration",
+		last,
+		fmt.Sprintf("INSERT INTO keep_migration (version) VALUES ($1) [%d]", len(migration)),
+		"COMMIT",
+	}, fake.trace())
+}
+func TestSQLSave(t *testing.T) {
+	db, fake := newFakeDB(t)
+	s := NewSQL(db)
+	err := s.Save([]flow.Node{
+		{
+			UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")),
+			Hook: option.Some(flow.Hook{"kind": "cat"}),
+			Live: option.Some(flow.Live{Since: option.Some(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))}),
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, fake.call, 3)
+	require.Equal(t, "BEGIN", fake.call[0].query)
+	require.True(t, strings.HasPrefix(fake.call[1].query, "INSERT INTO keep_node"))
+	require.Equal(t, "10000000-0000-0000-0000-000000000000", fake.call[1].args[0])
+	require.JSONEq(t,
+		`{"uuid":"10000000-0000-0000-0000-000000000000","hook":{"kind":"cat"},"live":{"since":"2024-01-01T00:00:00Z"}}`,
+		fake.call[1].args[1].(string),
+	)
+	require.JSONEq(t, `{"kind":"cat"}`, fake.call[1].args[2].(string))
+	require.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), fake.call[1].args[3])
+	require.Nil(t, fake.call[1].args[4])
+	require.Equal(t, "COMMIT", fake.call[2].query)
+}
+func TestSQLRead(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.query = func(query string, args []driver.Value) [][]driver.Value {
+		return [][]driver.Value{
+			{[]byte(`{"uuid":"10000000-0000-0000-0000-000000000000","hook":{"kind":"cat"}}`)},
+			{[]byte(`{"uuid":"20000000-0000-0000-0000-000000000000","hook":{"kind":"cat","age":1}}`)},
+		}
+	}
+	s := NewSQL(db)
+	w := flow.When{Hook: option.Some([]flow.Hook{{"kind": "cat"}})}
+	r := s.When(w).Read()
+	require.Equal(t,
+		[]flow.UUID{
+			flow.MustUUID("10000000-0000-0000-0000-000000000000"),
+			flow.MustUUID("20000000-0000-0000-0000-000000000000"),
+		},
+		slices.Collect(r.UUID()),
+	)
+	require.NoError(t, r.Err())
+	require.Equal(t,
+		`SELECT data FROM keep_node WHERE hook IS NOT NULL AND (hook @> $1::jsonb) ORDER BY uuid`,
+		fake.call[0].query,
+	)
+	require.Equal(t, []driver.Value{`{"kind":"cat"}`}, fake.call[0].args)
+}
+func TestSQLFill(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.query = func(query string, args []driver.Value) [][]driver.Value {
+		return [][]driver.Value{
+			{[]byte(`{"uuid":"20000000-0000-0000-0000-000000000000","meta":{"foo":"bar"},"origin":{"uuid":"20000000-0000-0000-0000-000000000000"}}`)},
+		}
+	}
+	s := NewSQL(db)
+	target := []flow.Node{
+		{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))},
+		{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))},
+	}
+	err := s.Fill(target)
+	require.NoError(t, err)
+	require.Equal(t,
+		`SELECT data FROM keep_node WHERE uuid IN ($1, $2)`,
+		fake.call[0].query,
+	)
+	require.Equal(t, flow.Meta{"foo": "bar"}, target[1].Meta.Get())
+	require.True(t, target[1].Equal(target[1].Origin()))
+	origin := target[1].Origin()
+	require.True(t, origin.Origin().IsZero())
+	require.True(t, target[0].Meta.IsZero())
+}
+func TestSQLWhen(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		name  string
+		when  flow.When
+		query string
+		args  []any
+	}{
+		{
+			name:  "zero",
+			when:  flow.When{},
+			query: `true`,
+		},
+		{
+			name:  "uuid none",
+			when:  flow.When{UUID: option.None[[]flow.UUID]()},
+			query: `false`,
+		},
+		{
+			name: "uuid some",
+			when: flow.When{UUID: option.Some([]flow.UUID{
+				flow.MustUUID("10000000-0000-0000-0000-000000000000"),
+				flow.MustUUID("20000000-0000-0000-0000-000000000000"),
+			})},
+			query: `uuid IN ($1, $2)`,
+			args:  []any{"10000000-0000-0000-0000-000000000000", "20000000-0000-0000-0000-000000000000"},
+		},
+		{
+			name:  "hook none",
+			when:  flow.When{Hook: option.None[[]flow.Hook]()},
+			query: `jsonb_typeof(data->'hook') = 'null'`,
+		},
+		{
+			name:  "hook some",
+			when:  flow.When{Hook: option.Some([]flow.Hook{{"a": "1"}, {"b": "2"}})},
+			query: `hook IS NOT NULL AND (hook @> $1::jsonb OR hook @> $2::jsonb)`,
+			args:  []any{`{"a":"1"}`, `{"b":"2"}`},
+		},
+		{
+			name:  "live some",
+			when:  flow.When{Live: option.Some([]flow.Live{{Since: option.Some(since)}})},
+			query: `jsonb_typeof(data->'live') = 'object' AND ((live_since IS NULL OR live_since >= $1))`,
+			args:  []any{since},
+		},
+		{
+			name:  "live empty",
+			when:  flow.When{Live: option.Some([]flow.Live{})},
+			query: `jsonb_typeof(data->'live') = 'object' AND false`,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var args []any
+			query, err := sqlWhen(c.when, &args)
+			require.NoError(t, err)
+			require.Equal(t, c.query, query)
+			require.Equal(t, c.args, args)
+		})
+	}
+}
+
+type fakeCall struct {
+	query string
+	args  []driver.Value
+}
+type fakeDB struct {
+	mu    sync.Mutex
+	call  []fakeCall
+	query func(query string, args []driver.Value) [][]driver.Value
+	exec  func(query string, args []driver.Value) int64
+}
+
+func newFakeDB(t *testing.T) (*sql.DB, *fakeDB) {
+	fake := &fakeDB{}
+	db := sql.OpenDB(fake)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db, fake
+}
+func (it *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{it}, nil }
+func (it *fakeDB) Driver() driver.Driver                        { return nil }
+func (it *fakeDB) record(query string, args []driver.Value) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	it.call = append(it.call, fakeCall{query: query, args: args})
+}
+func (it *fakeDB) reset() {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	it.call = nil
+}
+func (it *fakeDB) trace() (s []string) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	for _, c := range it.call {
+		var q, _, _ = strings.Cut(c.query, "\n")
+		if len(c.args) != 0 {
+			var b strings.Builder
+			b.WriteString(q + " [")
+			for i, a := range c.args {
+				if i != 0 {
+					b.WriteString(" ")
+				}
+				b.WriteString(fmt.Sprint(a))
+			}
+			b.WriteString("]")
+			q = b.String()
+		}
+		s = append(s, q)
+	}
+	return s
+}
+func TestSQLImportCreate(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.exec = func(query string, args []driver.Value) int64 {
+		if args[0] == "20000000-0000-0000-0000-000000000000" {
+			return 0
+		}
+		return 1
+	}
+	s := NewSQL(db)
+	err := s.Import(strings.NewReader(
+		`{"node":{"uuid":"10000000-0000-0000-0000-000000000000"}}`+"\n"+
+			`{"node":{"uuid":"20000000-0000-0000-0000-000000000000"}}`+"\n",
+	), ImportCreate)
+	var conflict *ConflictError
+	require.ErrorAs(t, err, &conflict)
+	require.Equal(t, []flow.UUID{flow.MustUUID("20000000-0000-0000-0000-000000000000")}, conflict.UUID)
+	require.Len(t, fake.call, 4)
+	require.Equal(t, "BEGIN", fake.call[0].query)
+	require.True(t, strings.HasSuffix(fake.call[1].query, "ON CONFLICT (uuid) DO NOTHING"))
+	require.Equal(t, "ROLLBACK", fake.call[3].query)
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (it fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{it.db, query}, nil }
+func (it fakeConn) Close() error                              { return nil }
+func (it fakeConn) Begin() (driver.Tx, error) {
+	it.db.record("BEGIN", nil)
+	return fakeTx(it), nil
+}
+func (it fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	it.db.record(query, namedValues(args))
+	return driver.RowsAffected(0), nil
+}
+func (it fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	it.db.record(query, namedValues(args))
+	var rows [][]driver.Value
+	if it.db.query != nil {
+		rows = it.db.query(query, namedValues(args))
+	}
+	return &fakeRows{rows: rows}, nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (it fakeStmt) Close() error  { return nil }
+func (it fakeStmt) NumInput() int { return -1 }
+func (it fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	it.db.record(it.query, args)
+	if it.db.exec != nil {
+		return driver.RowsAffected(it.db.exec(it.query, args)), nil
+	}
+	return driver.RowsAffected(0), nil
+}
+func (it fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	it.db.record(it.query, args)
+	return &fakeRows{}, nil
+}
+
+type fakeTx struct{ db *fakeDB }
+
+func (it fakeTx) Commit() error {
+	it.db.record("COMMIT", nil)
+	return nil
+}
+func (it fakeTx) Rollback() error {
+	it.db.record("ROLLBACK", nil)
+	return nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (it *fakeRows) Columns() []string {
+	if len(it.rows) == 0 {
+		return []string{"data"}
+	}
+	return make([]string, len(it.rows[0]))
+}
+func (it *fakeRows) Close() error { return nil }
+func (it *fakeRows) Next(dest []driver.Value) error {
+	if len(it.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, it.rows[0])
+	it.rows = it.rows[1:]
+	return nil
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	if len(args) == 0 {
+		return nil
+	}
+	var v = make([]driver.Value, len(args))
+	for i := range args {
+		v[i] = args[i].Value
+	}
+	return v
+}
+func TestSQLSaveConflict(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.query = func(query string, args []driver.Value) [][]driver.Value {
+		return [][]driver.Value{
+			{[]byte(`{"uuid":"10000000-0000-0000-0000-000000000000","hook":{"kind":"dog"}}`)},
+		}
+	}
+	s := NewSQL(db)
+	n := flow.Node{
+		UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")),
+		Hook: option.Some(flow.Hook{"kind": "bird"}),
+	}
+	n.SetOrigin(flow.Node{
+		UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")),
+		Hook: option.Some(flow.Hook{"kind": "cat"}),
+	})
+	err := s.Save([]flow.Node{n})
+	var conflict *ConflictError
+	require.ErrorAs(t, err, &conflict)
+	require.Equal(t, []flow.UUID{flow.MustUUID("10000000-0000-0000-0000-000000000000")}, conflict.UUID)
+	require.Equal(t, []string{
+		"BEGIN",
+		"SELECT data FROM keep_node WHERE uuid IN ($1) FOR UPDATE [10000000-0000-0000-0000-000000000000]",
+		"ROLLBACK",
+	}, fake.trace())
+}
@@ -0,0 +1,134 @@
+package keep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/typomaker/flow"
+	"github.com/typomaker/option"
+)
+
+// Sweep drops every stored node whose Live.Until has passed, or hands it to
+// Expire on each tick until Expire drops or extends it. Arrive only gets the
+// nodes whose Live.Since fell between two ticks.
+type Sweep struct {
+	Node   *Node
+	Every  time.Duration
+	Now    func() time.Time
+	Expire flow.Handler
+	Arrive flow.Handler
+	// Catchup is how far back the first Tick looks for arrived nodes.
+	// Nodes whose Since is older are never passed to Arrive.
+	Catchup time.Duration
+	mu      sync.Mutex
+	last    time.Time
+}
+
+func (it *Sweep) Run(ctx context.Context) (err error) {
+	var every = it.Every
+	if every <= 0 {
+		every = time.Minute
+	}
+	var t = time.NewTicker(every)
+	defer t.Stop()
+	for {
+		if err = it.Tick(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+func (it *Sweep) Tick(ctx context.Context) (err error) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	var now = it.now()
+	var since = it.last
+	if since.IsZero() {
+		since = now.Add(-it.Catchup)
+	}
+	var expired, arrived []flow.Node
+	if expired, err = it.read(flow.Live{Until: option.Some(now)}, liveUntil, time.Time{}, now); err != nil {
+		return err
+	}
+	if it.Arrive != nil {
+		if arrived, err = it.read(flow.Live{Since: option.Some(since)}, liveSince, since, now); err != nil {
+			return err
+		}
+	}
+	if len(expired) != 0 {
+		if it.Expire == nil {
+			err = it.drop(expired)
+		} else {
+			err = it.Expire(ctx, expired, noopNext)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(arrived) != 0 {
+		if err = it.Arrive(ctx, arrived, noopNext); err != nil {
+			return err
+		}
+	}
+	it.last = now
+	return nil
+}
+func (it *Sweep) drop(v []flow.Node) (err error) {
+	for len(v) != 0 {
+		var uuid = make([]flow.UUID, len(v))
+		for i := range v {
+			uuid[i] = v[i].UUID.Get()
+		}
+		var conflict *ConflictError
+		switch err = it.Node.load().commit(batch{drop: uuid, match: v}); {
+		case err == nil:
+			return nil
+		case !errors.As(err, &conflict):
+			return fmt.Errorf("keep: %w", err)
+		}
+		v = slices.DeleteFunc(v, func(n flow.Node) bool {
+			return slices.Contains(conflict.UUID, n.UUID.Get())
+		})
+	}
+	return nil
+}
+func (it *Sweep) read(l flow.Live, edge func(flow.Live) option.Option[time.Time], from, now time.Time) (v []flow.Node, err error) {
+	var r = it.Node.When(flow.When{Live: option.Some([]flow.Live{l})}).Read()
+	for n := range r.Full() {
+		var t = edge(n.Live.Get())
+		if !t.IsSome() || !t.Get().After(from) || t.Get().After(now) {
+			continue
+		}
+		n.SetOrigin(bareNode(n))
+		v = append(v, n)
+	}
+	if err = r.Err(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+func (it *Sweep) now() time.Time {
+	if it.Now != nil {
+		return it.Now()
+	}
+	return time.Now()
+}
+
+func liveUntil(l flow.Live) option.Option[time.Time] {
+	return l.Until
+}
+func liveSince(l flow.Live) option.Option[time.Time] {
+	return l.Since
+}
+func noopNext([]flow.Node) error {
+	return nil
+}
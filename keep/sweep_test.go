@@ -0,0 +1,170 @@
+package keep
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/typomaker/flow"
+	"github.com/typomaker/option"
+)
+
+func TestSweepDrop(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &Node{}
+	err := s.Save([]flow.Node{
+		{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")), Live: option.Some(flow.Live{Until: option.Some(now.Add(time.Hour))})},
+		{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000")), Live: option.Some(flow.Live{Until: option.Some(now.Add(2 * time.Hour))})},
+		{UUID: option.Some(flow.MustUUID("30000000-0000-0000-0000-000000000000")), Live: option.Some(flow.Live{Since: option.Some(now.Add(-time.Hour))})},
+		{UUID: option.Some(flow.MustUUID("40000000-0000-0000-0000-000000000000"))},
+	})
+	require.NoError(t, err)
+
+	w := &Sweep{Node: s, Now: func() time.Time { return now }}
+	require.NoError(t, w.Tick(context.Background()))
+	require.Len(t, slicesCollect(s), 4)
+
+	now = now.Add(time.Hour)
+	require.NoError(t, w.Tick(context.Background()))
+	require.Equal(t,
+		[]flow.UUID{
+			flow.MustUUID("20000000-0000-0000-0000-000000000000"),
+			flow.MustUUID("30000000-0000-0000-0000-000000000000"),
+			flow.MustUUID("40000000-0000-0000-0000-000000000000"),
+		},
+		slices.Collect(s.When(flow.When{}).Read().UUID()),
+	)
+
+	now = now.Add(time.Hour)
+	require.NoError(t, w.Tick(context.Background()))
+	require.Len(t, slicesCollect(s), 2)
+}
+func TestSweepHandler(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &Node{}
+	err := s.Save([]flow.Node{
+		{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")), Live: option.Some(flow.Live{Until: option.Some(now.Add(time.Hour))})},
+		{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000")), Live: option.Some(flow.Live{Since: option.Some(now.Add(time.Hour))})},
+	})
+	require.NoError(t, err)
+
+	var expired, arrived []flow.UUID
+	w := &Sweep{
+		Node: s,
+		Now:  func() time.Time { return now },
+		Expire: func(ctx context.Context, target []flow.Node, next flow.Next) error {
+			for _, n := range target {
+				expired = append(expired, n.UUID.Get())
+				require.True(t, n.Equal(n.Origin()))
+			}
+			return next(target)
+		},
+		Arrive: func(ctx context.Context, target []flow.Node, next flow.Next) error {
+			for _, n := range target {
+				arrived = append(arrived, n.UUID.Get())
+			}
+			return next(target)
+		},
+	}
+	require.NoError(t, w.Tick(context.Background()))
+	require.Empty(t, expired)
+	require.Empty(t, arrived)
+
+	now = now.Add(time.Hour)
+	require.NoError(t, w.Tick(context.Background()))
+	require.Equal(t, []flow.UUID{flow.MustUUID("10000000-0000-0000-0000-000000000000")}, expired)
+	require.Equal(t, []flow.UUID{flow.MustUUID("20000000-0000-0000-0000-000000000000")}, arrived)
+
+	now = now.Add(time.Hour)
+	require.NoError(t, w.Tick(context.Background()))
+	require.Len(t, expired, 2)
+	require.Len(t, arrived, 1)
+	require.Len(t, slicesCollect(s), 2)
+}
+func TestSweepRun(t *testing.T) {
+	s := &Node{}
+	err := s.Save([]flow.Node{
+		{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")), Live: option.Some(flow.Live{Until: option.Some(time.Now().Add(-time.Hour))})},
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, (&Sweep{Node: s, Every: time.Millisecond}).Run(ctx))
+	require.Empty(t, slicesCollect(s))
+}
+func TestSweepCatchup(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &Node{}
+	err := s.Save([]flow.Node{
+		{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")), Live: option.Some(flow.Live{Since: option.Some(now.Add(-24 * time.Hour))})},
+		{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000")), Live: option.Some(flow.Live{Since: option.Some(now.Add(-time.Minute))})},
+	})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		catchup time.Duration
+		arrived []flow.UUID
+	}{
+		{0, nil},
+		{time.Hour, []flow.UUID{flow.MustUUID("20000000-0000-0000-0000-000000000000")}},
+	} {
+		var arrived []flow.UUID
+		w := &Sweep{
+			Node:    s,
+			Now:     func() time.Time { return now },
+			Catchup: tc.catchup,
+			Arrive: func(ctx context.Context, target []flow.Node, next flow.Next) error {
+				for _, n := range target {
+					arrived = append(arrived, n.UUID.Get())
+				}
+				return next(target)
+			},
+		}
+		require.NoError(t, w.Tick(context.Background()))
+		require.Equal(t, tc.arrived, arrived)
+		require.NoError(t, w.Tick(context.Background()))
+		require.Equal(t, tc.arrived, arrived)
+	}
+}
+func TestSweepLate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, open := range backend() {
+		t.Run(name, func(t *testing.T) {
+			s := open(t)
+			w := &Sweep{Node: s, Now: func() time.Time { return now }}
+			require.NoError(t, w.Tick(context.Background()))
+
+			err := s.Save([]flow.Node{
+				{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")), Live: option.Some(flow.Live{Until: option.Some(now.Add(-time.Hour))})},
+				{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000")), Live: option.Some(flow.Live{Until: option.Some(now.Add(-time.Hour))})},
+			})
+			require.NoError(t, err)
+			now = now.Add(time.Minute)
+			expired, err := w.read(flow.Live{Until: option.Some(now)}, liveUntil, time.Time{}, now)
+			require.NoError(t, err)
+			require.Len(t, expired, 2)
+
+			err = s.Save([]flow.Node{
+				{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000")), Live: option.Some(flow.Live{Until: option.Some(now.Add(time.Hour))})},
+			})
+			require.NoError(t, err)
+			require.NoError(t, w.drop(expired))
+			require.Equal(t,
+				[]flow.UUID{flow.MustUUID("20000000-0000-0000-0000-000000000000")},
+				slices.Collect(s.When(flow.When{}).Read().UUID()),
+			)
+
+			err = s.Save([]flow.Node{
+				{UUID: option.Some(flow.MustUUID("30000000-0000-0000-0000-000000000000")), Live: option.Some(flow.Live{Until: option.Some(now.Add(-time.Hour))})},
+			})
+			require.NoError(t, err)
+			require.NoError(t, w.Tick(context.Background()))
+			require.Equal(t,
+				[]flow.UUID{flow.MustUUID("20000000-0000-0000-0000-000000000000")},
+				slices.Collect(s.When(flow.When{}).Read().UUID()),
+			)
+		})
+	}
+}
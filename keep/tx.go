@@ -0,0 +1,261 @@
+package keep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/typomaker/flow"
+)
+
+var ErrTxDone = errors.New("keep: transaction is done")
+
+type batch struct {
+	save []flow.Node
+	drop []flow.UUID
+	kase []Case
+	// match holds nodes that are only checked against their origin.
+	match []flow.Node
+	// restore skips the origin check, the saved origins are stored as is.
+	restore bool
+	// create fails the batch when a saved node or case already exists.
+	create bool
+}
+
+func (it batch) origin() []flow.Node {
+	if it.restore {
+		return it.match
+	}
+	return append(slices.Clip(it.save), it.match...)
+}
+
+type contextTxKey struct {
+	node *Node
+}
+
+// Begin starts a transaction and carries it through the returned context.
+// Begin inside a transaction starts a nested scope: its Commit merges the
+// writes into the outer transaction and its Rollback discards only them.
+func (it *Node) Begin(ctx context.Context) (context.Context, *Tx) {
+	var tx = &Tx{keep: it, outer: it.Tx(ctx), node: make(map[flow.UUID]txNode)}
+	return context.WithValue(ctx, contextTxKey{node: it}, tx), tx
+}
+func (it *Node) Tx(ctx context.Context) *Tx {
+	if tx, ok := ctx.Value(contextTxKey{node: it}).(*Tx); ok {
+		return tx
+	}
+	return nil
+}
+
+func Atomic(s *Node, h flow.Handler) flow.Handler {
+	return func(ctx context.Context, target []flow.Node, next flow.Next) (err error) {
+		var tx *Tx
+		ctx, tx = s.Begin(ctx)
+		defer tx.Rollback()
+
+		if err = h(ctx, target, next); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+}
+
+type Tx struct {
+	keep  *Node
+	outer *Tx
+	mu    sync.Mutex
+	done  bool
+	order []flow.UUID
+	node  map[flow.UUID]txNode
+	kase  []Case
+}
+type txNode struct {
+	node flow.Node
+	drop bool
+}
+
+func (it *Tx) root() *Tx {
+	for it.outer != nil {
+		it = it.outer
+	}
+	return it
+}
+func (it *Tx) closed() bool {
+	for ; it != nil; it = it.outer {
+		if it.done {
+			return true
+		}
+	}
+	return false
+}
+func (it *Tx) lookup(u flow.UUID) (txNode, bool) {
+	for ; it != nil; it = it.outer {
+		if p, ok := it.node[u]; ok {
+			return p, true
+		}
+	}
+	return txNode{}, false
+}
+func (it *Tx) put(u flow.UUID, p txNode) {
+	if _, ok := it.node[u]; !ok {
+		it.order = append(it.order, u)
+	}
+	it.node[u] = p
+}
+func (it *Tx) Fill(v []flow.Node) (err error) {
+	if err = checkNode(v); err != nil {
+		return err
+	}
+	sortNode(v)
+
+	var r = it.root()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if it.closed() {
+		return ErrTxDone
+	}
+	var index = make([]int, 0, len(v))
+	var rest = make([]flow.Node, 0, len(v))
+	for i := range v {
+		switch p, ok := it.lookup(v[i].UUID.Get()); {
+		case !ok:
+			index = append(index, i)
+			rest = append(rest, v[i])
+		case !p.drop:
+			v[i] = p.node.Copy()
+		}
+	}
+	if err = it.keep.Fill(rest); err != nil {
+		return err
+	}
+	for i, n := range rest {
+		v[index[i]] = n
+	}
+	return nil
+}
+func (it *Tx) Save(v []flow.Node) (err error) {
+	if err = checkNode(v); err != nil {
+		return err
+	}
+	sortNode(v)
+
+	var r = it.root()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if it.closed() {
+		return ErrTxDone
+	}
+	for i := range v {
+		var u = v[i].UUID.Get()
+		var n = v[i].Copy()
+		switch p, ok := it.lookup(u); {
+		case !ok:
+		case p.drop:
+			n.SetOrigin(flow.Node{})
+		default:
+			n.SetOrigin(p.node.Origin())
+		}
+		it.put(u, txNode{node: n})
+	}
+	for i := range v {
+		if !v[i].Origin().IsZero() {
+			v[i].SetOrigin(bareNode(v[i]))
+		}
+	}
+	return nil
+}
+func (it *Tx) Drop(v []flow.Node) (err error) {
+	if err = checkNode(v); err != nil {
+		return err
+	}
+	sortNode(v)
+
+	var r = it.root()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if it.closed() {
+		return ErrTxDone
+	}
+	for i := range v {
+		it.put(v[i].UUID.Get(), txNode{drop: true})
+	}
+	return nil
+}
+func (it *Tx) Case(v []flow.Case) (err error) {
+	var r = it.root()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if it.closed() {
+		return ErrTxDone
+	}
+	var now = time.Now()
+	for i := range v {
+		it.kase = append(it.kase, copyCase(Case{UUID: flow.NewUUID(), Time: now, Status: StatusPending, Case: v[i]}))
+	}
+	return nil
+}
+func (it *Tx) Commit() (err error) {
+	var r = it.root()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if it.closed() {
+		return ErrTxDone
+	}
+	it.done = true
+	if it.outer != nil {
+		for _, u := range it.order {
+			it.outer.put(u, it.node[u])
+		}
+		it.outer.kase = append(it.outer.kase, it.kase...)
+		it.order, it.node, it.kase = nil, nil, nil
+		return nil
+	}
+	var b = batch{kase: it.kase}
+	for _, u := range it.order {
+		if p := it.node[u]; p.drop {
+			b.drop = append(b.drop, u)
+		} else {
+			b.save = append(b.save, p.node)
+		}
+	}
+	sortNode(b.save)
+	if err = it.keep.load().commit(b); err != nil {
+		return fmt.Errorf("keep: %w", err)
+	}
+	return nil
+}
+func (it *Tx) Rollback() error {
+	var r = it.root()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if it.closed() {
+		return ErrTxDone
+	}
+	it.done = true
+	it.order, it.node, it.kase = nil, nil, nil
+	return nil
+}
+func (it *Tx) overlay(uuid []flow.UUID, found map[flow.UUID]flow.Node) {
+	var r = it.root()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range uuid {
+		switch p, ok := it.lookup(u); {
+		case !ok:
+		case p.drop:
+			delete(found, u)
+		default:
+			found[u] = bareNode(p.node)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package keep
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/typomaker/flow"
+	"github.com/typomaker/flow/goja"
+	"github.com/typomaker/option"
+)
+
+func TestTx(t *testing.T) {
+	for name, open := range backend() {
+		t.Run(name, func(t *testing.T) {
+			s := open(t)
+			err := s.Save([]flow.Node{{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))}})
+			require.NoError(t, err)
+
+			ctx, tx := s.Begin(context.Background())
+			require.Same(t, tx, s.Tx(ctx))
+			require.NoError(t, tx.Save([]flow.Node{{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000")), Hook: option.Some(flow.Hook{"kind": "cat"})}}))
+			require.NoError(t, tx.Drop([]flow.Node{{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))}}))
+			require.NoError(t, tx.Case([]flow.Case{{Then: flow.Then{Kind: option.Some("feed")}}}))
+
+			target := []flow.Node{
+				{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))},
+				{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))},
+			}
+			require.NoError(t, tx.Fill(target))
+			require.True(t, target[0].Hook.IsZero())
+			require.Equal(t, flow.Hook{"kind": "cat"}, target[1].Hook.Get())
+
+			require.Equal(t, []flow.UUID{flow.MustUUID("10000000-0000-0000-0000-000000000000")}, slices.Collect(s.When(flow.When{}).Read().UUID()))
+			require.Empty(t, slices.Collect(s.Cases(CaseWhen{}).Read().Full()))
+
+			require.NoError(t, tx.Commit())
+			require.ErrorIs(t, tx.Commit(), ErrTxDone)
+			require.ErrorIs(t, tx.Rollback(), ErrTxDone)
+			require.Equal(t, []flow.UUID{flow.MustUUID("20000000-0000-0000-0000-000000000000")}, slices.Collect(s.When(flow.When{}).Read().UUID()))
+			require.Len(t, slices.Collect(s.Cases(CaseWhen{}).Read().Full()), 1)
+
+			_, tx = s.Begin(context.Background())
+			require.NoError(t, tx.Drop([]flow.Node{{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))}}))
+			require.NoError(t, tx.Rollback())
+			require.ErrorIs(t, tx.Save([]flow.Node{{UUID: option.Some(flow.MustUUID("30000000-0000-0000-0000-000000000000"))}}), ErrTxDone)
+			require.Len(t, slicesCollect(s), 1)
+		})
+	}
+}
+func TestTxNested(t *testing.T) {
+	s := &Node{}
+	ctx, tx := s.Begin(context.Background())
+	require.NoError(t, tx.Save([]flow.Node{{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")), Hook: option.Some(flow.Hook{"kind": "cat"})}}))
+
+	inner, child := s.Begin(ctx)
+	require.Same(t, child, s.Tx(inner))
+	require.NoError(t, child.Save([]flow.Node{{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))}}))
+	require.NoError(t, child.Drop([]flow.Node{{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))}}))
+	require.NoError(t, child.Case([]flow.Case{{Then: flow.Then{Kind: option.Some("feed")}}}))
+	target := []flow.Node{{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))}}
+	require.NoError(t, child.Fill(target))
+	require.True(t, target[0].Hook.IsZero())
+	require.NoError(t, child.Rollback())
+	require.ErrorIs(t, child.Commit(), ErrTxDone)
+
+	target = []flow.Node{{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))}}
+	require.NoError(t, tx.Fill(target))
+	require.Equal(t, flow.Hook{"kind": "cat"}, target[0].Hook.Get())
+
+	_, child = s.Begin(ctx)
+	require.NoError(t, child.Save([]flow.Node{{UUID: option.Some(flow.MustUUID("30000000-0000-0000-0000-000000000000"))}}))
+	require.NoError(t, child.Case([]flow.Case{{Then: flow.Then{Kind: option.Some("walk")}}}))
+	require.NoError(t, child.Commit())
+	require.Empty(t, slicesCollect(s))
+
+	require.NoError(t, tx.Commit())
+	require.Equal(t,
+		[]flow.UUID{
+			flow.MustUUID("10000000-0000-0000-0000-000000000000"),
+			flow.MustUUID("30000000-0000-0000-0000-000000000000"),
+		},
+		slices.Collect(s.When(flow.When{}).Read().UUID()),
+	)
+	kase := slices.Collect(s.Cases(CaseWhen{}).Read().Full())
+	require.Len(t, kase, 1)
+	require.Equal(t, "walk", kase[0].Case.Then.Kind.Get())
+}
+func TestTxConflict(t *testing.T) {
+	s := &Node{}
+	n := flow.Node{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")), Meta: option.Some(flow.Meta{"count": float64(1)})}
+	require.NoError(t, s.Save([]flow.Node{n}))
+
+	_, tx := s.Begin(context.Background())
+	a := n.Copy()
+	a.SetOrigin(n.Copy())
+	a.Meta = option.Some(flow.Meta{"count": float64(2)})
+	require.NoError(t, tx.Save([]flow.Node{a}))
+	a.Meta = option.Some(flow.Meta{"count": float64(3)})
+	require.NoError(t, tx.Save([]flow.Node{a}))
+	require.NoError(t, tx.Case([]flow.Case{{Then: flow.Then{Kind: option.Some("feed")}}}))
+
+	b := n.Copy()
+	b.SetOrigin(n.Copy())
+	b.Meta = option.Some(flow.Meta{"count": float64(4)})
+	require.NoError(t, s.Save([]flow.Node{b}))
+
+	err := tx.Commit()
+	require.ErrorIs(t, err, ErrConflict)
+	require.Empty(t, slices.Collect(s.Cases(CaseWhen{}).Read().Full()))
+	require.Equal(t, flow.Meta{"count": float64(4)}, slicesCollect(s)[0].Meta.Get())
+}
+func TestAtomic(t *testing.T) {
+	s := &Node{}
+	err := s.Save([]flow.Node{
+		{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")), Meta: option.Some(flow.Meta{"count": float64(1)})},
+	})
+	require.NoError(t, err)
+
+	fail := errors.New("fail")
+	var failing bool
+	f := flow.New(
+		flow.FS(fstest.MapFS{
+			"x.js": &fstest.MapFile{
+				Data: []byte(`
+					export default function main(nodes, next) {
+						for (const node of nodes) {
+							node.meta.count++
+							this.notify({when: {uuid: [node.uuid]}, then: {kind: "count"}})
+						}
+						next(nodes)
+					}
+				`),
+			},
+		}),
+		Atomic(s, flow.Pipe(
+			Load(s),
+			goja.New("x.js"),
+			Store(s),
+			Atomic(s, func(ctx context.Context, target []flow.Node, next flow.Next) error {
+				if failing {
+					return fail
+				}
+				return next(target)
+			}),
+		)),
+	)
+	target := []flow.Node{{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))}}
+	require.NoError(t, f.Run(context.Background(), target, Notifier(s)))
+	require.Equal(t, flow.Meta{"count": float64(2)}, slicesCollect(s)[0].Meta.Get())
+	require.Len(t, slices.Collect(s.Cases(CaseWhen{}).Read().Full()), 1)
+
+	failing = true
+	target = []flow.Node{{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))}}
+	require.ErrorIs(t, f.Run(context.Background(), target, Notifier(s)), fail)
+	require.Equal(t, flow.Meta{"count": float64(2)}, slicesCollect(s)[0].Meta.Get())
+	require.Len(t, slices.Collect(s.Cases(CaseWhen{}).Read().Full()), 1)
+}
+func TestSQLTx(t *testing.T) {
+	db, fake := newFakeDB(t)
+	s := NewSQL(db)
+	_, tx := s.Begin(context.Background())
+	require.NoError(t, tx.Save([]flow.Node{{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000"))}}))
+	require.NoError(t, tx.Drop([]flow.Node{{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000"))}}))
+	require.NoError(t, tx.Case([]flow.Case{{Then: flow.Then{Kind: option.Some("feed")}}}))
+	require.Empty(t, fake.call)
+	require.NoError(t, tx.Commit())
+
+	trace := fake.trace()
+	require.Len(t, trace, 5)
+	require.Equal(t, "BEGIN", trace[0])
+	require.Contains(t, trace[1], "INSERT INTO keep_node")
+	require.Equal(t, "DELETE FROM keep_node WHERE uuid IN ($1) [20000000-0000-0000-0000-000000000000]", trace[2])
+	require.Contains(t, trace[3], "INSERT INTO keep_case")
+	require.Equal(t, "COMMIT", trace[4])
+}
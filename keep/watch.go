@@ -0,0 +1,141 @@
+package keep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"sync"
+
+	"github.com/typomaker/flow"
+)
+
+var ErrWatch = errors.New("keep: watch is not supported")
+
+type Event uint8
+
+const (
+	EventCreated Event = iota + 1
+	EventUpdated
+	EventDropped
+)
+
+func (it Event) String() string {
+	switch it {
+	case EventCreated:
+		return "created"
+	case EventUpdated:
+		return "updated"
+	case EventDropped:
+		return "dropped"
+	default:
+		return fmt.Sprintf("event(%d)", uint8(it))
+	}
+}
+
+type Change struct {
+	Event  Event
+	Before flow.Node
+	After  flow.Node
+}
+
+func (it Change) match(w flow.When) bool {
+	switch it.Event {
+	case EventCreated:
+		return it.After.When(w)
+	case EventDropped:
+		return it.Before.When(w)
+	default:
+		return it.Before.When(w) || it.After.When(w)
+	}
+}
+
+type watcher interface {
+	watch(ctx context.Context, w flow.When) iter.Seq[Change]
+}
+
+func (it When) Watch(ctx context.Context) (_ iter.Seq[Change], err error) {
+	var w, ok = it.node.load().(watcher)
+	if !ok {
+		return nil, ErrWatch
+	}
+	return w.watch(ctx, it.when), nil
+}
+
+type hub struct {
+	mu  sync.Mutex
+	sub map[*subscriber]struct{}
+}
+type subscriber struct {
+	when  flow.When
+	mu    sync.Mutex
+	queue []Change
+	wake  chan struct{}
+}
+
+func (it *hub) publish(v []Change) {
+	if len(v) == 0 {
+		return
+	}
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	for s := range it.sub {
+		s.push(v)
+	}
+}
+func (it *hub) watch(ctx context.Context, w flow.When) iter.Seq[Change] {
+	var s = &subscriber{when: w, wake: make(chan struct{}, 1)}
+	it.mu.Lock()
+	if it.sub == nil {
+		it.sub = make(map[*subscriber]struct{})
+	}
+	it.sub[s] = struct{}{}
+	it.mu.Unlock()
+
+	var stop = context.AfterFunc(ctx, func() { it.leave(s) })
+	return func(yield func(Change) bool) {
+		defer stop()
+		defer it.leave(s)
+
+		for {
+			for _, c := range s.pop() {
+				if ctx.Err() != nil || !yield(c) {
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wake:
+			}
+		}
+	}
+}
+func (it *hub) leave(s *subscriber) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	delete(it.sub, s)
+}
+func (it *subscriber) push(v []Change) {
+	it.mu.Lock()
+	for i := range v {
+		if v[i].match(it.when) {
+			it.queue = append(it.queue, Change{Event: v[i].Event, Before: v[i].Before.Copy(), After: v[i].After.Copy()})
+		}
+	}
+	it.mu.Unlock()
+
+	select {
+	case it.wake <- struct{}{}:
+	default:
+	}
+}
+func (it *subscriber) pop() (v []Change) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	v, it.queue = it.queue, nil
+	return v
+}
@@ -0,0 +1,100 @@
+package keep
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/typomaker/flow"
+	"github.com/typomaker/flow/goja"
+	"github.com/typomaker/option"
+)
+
+func TestWatch(t *testing.T) {
+	s := &Node{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	seq, err := s.When(flow.When{Hook: option.Some([]flow.Hook{{"kind": "cat"}})}).Watch(ctx)
+	require.NoError(t, err)
+
+	cat := flow.Node{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")), Hook: option.Some(flow.Hook{"kind": "cat"})}
+	dog := flow.Node{UUID: option.Some(flow.MustUUID("20000000-0000-0000-0000-000000000000")), Hook: option.Some(flow.Hook{"kind": "dog"})}
+	require.NoError(t, s.Save([]flow.Node{cat, dog}))
+	require.NoError(t, s.Save([]flow.Node{cat}))
+	fat := cat.Copy()
+	fat.Meta = option.Some(flow.Meta{"fat": true})
+	require.NoError(t, s.Save([]flow.Node{fat}))
+	require.NoError(t, s.Drop([]flow.Node{cat, dog}))
+
+	var change []Change
+	for c := range seq {
+		change = append(change, c)
+		if len(change) == 3 {
+			cancel()
+		}
+	}
+	require.Len(t, change, 3)
+	require.Equal(t, EventCreated, change[0].Event)
+	require.True(t, change[0].Before.IsZero())
+	require.True(t, cat.Equal(change[0].After))
+	require.Equal(t, EventUpdated, change[1].Event)
+	require.True(t, cat.Equal(change[1].Before))
+	require.True(t, fat.Equal(change[1].After))
+	require.Equal(t, EventDropped, change[2].Event)
+	require.True(t, fat.Equal(change[2].Before))
+	require.True(t, change[2].After.IsZero())
+}
+func TestWatchCancel(t *testing.T) {
+	s := &Node{}
+	ctx, cancel := context.WithCancel(context.Background())
+	seq, err := s.When(flow.When{}).Watch(ctx)
+	require.NoError(t, err)
+	cancel()
+	for range seq {
+		t.Fatal("unexpected change")
+	}
+	m := s.load().(*memory)
+	m.hub.mu.Lock()
+	defer m.hub.mu.Unlock()
+	require.Empty(t, m.sub)
+}
+func TestWatchRun(t *testing.T) {
+	s := &Node{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	seq, err := s.When(flow.When{Hook: option.Some([]flow.Hook{{"kind": "cat"}})}).Watch(ctx)
+	require.NoError(t, err)
+
+	f := flow.New(
+		flow.FS(fstest.MapFS{
+			"x.js": &fstest.MapFile{
+				Data: []byte(`
+					export default function main(nodes, next) {
+						for (const node of nodes) {
+							node.meta = {seen: true}
+						}
+						next(nodes)
+					}
+				`),
+			},
+		}),
+		flow.Pipe(Load(s), goja.New("x.js"), Store(s)),
+	)
+	err = s.Save([]flow.Node{{UUID: option.Some(flow.MustUUID("10000000-0000-0000-0000-000000000000")), Hook: option.Some(flow.Hook{"kind": "cat"})}})
+	require.NoError(t, err)
+	for c := range seq {
+		if c.Event == EventCreated {
+			require.NoError(t, f.Run(ctx, []flow.Node{{UUID: c.After.UUID}}))
+			continue
+		}
+		require.Equal(t, EventUpdated, c.Event)
+		require.Equal(t, flow.Meta{"seen": true}, c.After.Meta.Get())
+		cancel()
+	}
+}
+func TestWatchUnsupported(t *testing.T) {
+	db, _ := newFakeDB(t)
+	_, err := NewSQL(db).When(flow.When{}).Watch(context.Background())
+	require.ErrorIs(t, err, ErrWatch)
+}
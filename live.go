@@ -62,13 +62,13 @@ func (it Live) MarshalJSON() (b []byte, err error) {
 }
 func (it *Live) UnmarshalJSON(b []byte) (err error) {
 	var js _LiveJSON
-	if err = jsoniter.Unmarshal(b, js); err != nil {
+	if err = jsoniter.Unmarshal(b, &js); err != nil {
 		return err
 	}
-	if err = jsoniter.Unmarshal(js.Since, &it.Since); err != nil {
+	if err = unmarshalRaw(js.Since, &it.Since); err != nil {
 		return fmt.Errorf("since: %w", err)
 	}
-	if err = jsoniter.Unmarshal(js.Until, &it.Until); err != nil {
+	if err = unmarshalRaw(js.Until, &it.Until); err != nil {
 		return fmt.Errorf("until: %w", err)
 	}
 	return nil
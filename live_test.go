@@ -63,3 +63,10 @@ func TestLiveLog(t *testing.T) {
 	})
 
 }
+func TestLiveJSON(t *testing.T) {
+	v := Live{}
+	err := v.UnmarshalJSON([]byte(`{"since":"1970-01-01T00:00:00Z"}`))
+	require.NoError(t, err)
+	require.True(t, v.Since.Get().Equal(time.Unix(0, 0)))
+	require.True(t, v.Until.IsZero())
+}
@@ -0,0 +1,145 @@
+package flow
+
+import (
+	"slices"
+	"time"
+)
+
+type Matcher struct {
+	rule    []When
+	uuid    map[UUID][]int
+	hook    map[string]map[any][]int
+	hookKey map[string][]int
+	hookAny []int
+	since   []matcherBound
+	until   []matcherBound
+	liveAny []int
+	rest    []int
+}
+type matcherBound struct {
+	time time.Time
+	rule int
+}
+
+func NewMatcher(w []When) *Matcher {
+	var it = &Matcher{
+		rule:    slices.Clone(w),
+		uuid:    make(map[UUID][]int),
+		hook:    make(map[string]map[any][]int),
+		hookKey: make(map[string][]int),
+	}
+	for i := range it.rule {
+		it.add(i)
+	}
+	slices.SortFunc(it.since, func(a, b matcherBound) int { return a.time.Compare(b.time) })
+	slices.SortFunc(it.until, func(a, b matcherBound) int { return b.time.Compare(a.time) })
+	return it
+}
+func (it *Matcher) add(i int) {
+	var w = it.rule[i]
+	switch {
+	case w.UUID.IsSome():
+		for _, u := range w.UUID.Get() {
+			it.uuid[u] = append(it.uuid[u], i)
+		}
+	case w.Hook.IsSome():
+		for _, h := range w.Hook.Get() {
+			it.addHook(i, h)
+		}
+	case w.Live.IsSome():
+		for _, l := range w.Live.Get() {
+			switch {
+			case l.Since.IsSome():
+				it.since = append(it.since, matcherBound{time: l.Since.Get(), rule: i})
+			case l.Until.IsSome():
+				it.until = append(it.until, matcherBound{time: l.Until.Get(), rule: i})
+			default:
+				it.liveAny = append(it.liveAny, i)
+			}
+		}
+	default:
+		it.rest = append(it.rest, i)
+	}
+}
+func (it *Matcher) addHook(i int, h Hook) {
+	var key string
+	for k, v := range h {
+		switch {
+		case v == nil:
+			continue
+		case matcherScalar(v):
+			if it.hook[k] == nil {
+				it.hook[k] = make(map[any][]int)
+			}
+			it.hook[k][v] = append(it.hook[k][v], i)
+			return
+		case key == "" || k < key:
+			key = k
+		}
+	}
+	if key != "" {
+		it.hookKey[key] = append(it.hookKey[key], i)
+		return
+	}
+	it.hookAny = append(it.hookAny, i)
+}
+func (it *Matcher) Match(n Node) (v []int) {
+	v = append(v, it.rest...)
+	if n.UUID.IsSome() {
+		v = append(v, it.uuid[n.UUID.Get()]...)
+	}
+	if n.Hook.IsSome() {
+		v = append(v, it.hookAny...)
+		for k, val := range n.Hook.Get() {
+			v = append(v, it.hookKey[k]...)
+			if matcherScalar(val) {
+				v = append(v, it.hook[k][val]...)
+			}
+		}
+	}
+	if n.Live.IsSome() {
+		var l = n.Live.Get()
+		v = append(v, it.liveAny...)
+		var since = len(it.since)
+		if l.Since.IsSome() {
+			since, _ = slices.BinarySearchFunc(it.since, l.Since.Get(), func(b matcherBound, t time.Time) int {
+				if b.time.After(t) {
+					return 1
+				}
+				return -1
+			})
+		}
+		for _, b := range it.since[:since] {
+			v = append(v, b.rule)
+		}
+		var until = len(it.until)
+		if l.Until.IsSome() {
+			until, _ = slices.BinarySearchFunc(it.until, l.Until.Get(), func(b matcherBound, t time.Time) int {
+				if b.time.Before(t) {
+					return 1
+				}
+				return -1
+			})
+		}
+		for _, b := range it.until[:until] {
+			v = append(v, b.rule)
+		}
+	}
+	slices.Sort(v)
+	v = slices.Compact(v)
+	return slices.DeleteFunc(v, func(i int) bool {
+		return !n.When(it.rule[i])
+	})
+}
+
+func matcherScalar(v any) bool {
+	switch v.(type) {
+	case string, bool,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return true
+	default:
+		return false
+	}
+}
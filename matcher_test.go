@@ -0,0 +1,129 @@
+package flow
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/typomaker/option"
+)
+
+func TestMatcher(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cat := MustUUID("10000000-0000-0000-0000-000000000000")
+	m := NewMatcher([]When{
+		{UUID: option.Some([]UUID{cat})},
+		{Hook: option.Some([]Hook{{"kind": "cat"}})},
+		{Hook: option.Some([]Hook{{"kind": "dog"}, {"tag": []any{"pet"}}})},
+		{Live: option.Some([]Live{{Since: option.Some(since)}})},
+		{Live: option.Some([]Live{{Until: option.Some(since)}})},
+		{},
+		{UUID: option.None[[]UUID]()},
+		{Hook: option.Some([]Hook{{"missing": nil}})},
+	})
+	require.Equal(t, []int{0, 1, 5, 7}, m.Match(Node{UUID: option.Some(cat), Hook: option.Some(Hook{"kind": "cat"})}))
+	require.Equal(t, []int{2, 5, 6, 7}, m.Match(Node{UUID: option.None[UUID](), Hook: option.Some(Hook{"kind": "bird", "tag": []any{"pet", "wild"}})}))
+	require.Equal(t, []int{3, 4, 5}, m.Match(Node{Live: option.Some(Live{Since: option.Some(since.Add(time.Hour))})}))
+	require.Equal(t, []int{3, 5}, m.Match(Node{Live: option.Some(Live{Since: option.Some(since), Until: option.Some(since.Add(time.Hour))})}))
+	require.Equal(t, []int{3, 4, 5}, m.Match(Node{Live: option.Some(Live{Since: option.Some(since), Until: option.Some(since)})}))
+	require.Equal(t, []int{5}, m.Match(Node{}))
+}
+func TestMatcherNaive(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	rule, node := matcherFixture(r, 500, 500)
+	m := NewMatcher(rule)
+	for _, n := range node {
+		var want []int
+		for i := range rule {
+			if n.When(rule[i]) {
+				want = append(want, i)
+			}
+		}
+		require.Equal(t, want, m.Match(n), "%+v", n)
+	}
+}
+func BenchmarkMatcher(b *testing.B) {
+	r := rand.New(rand.NewPCG(1, 2))
+	rule, node := matcherFixture(r, 5000, 1000)
+	b.Run("naive", func(b *testing.B) {
+		for range b.N {
+			for _, n := range node {
+				for i := range rule {
+					_ = n.When(rule[i])
+				}
+			}
+		}
+	})
+	b.Run("indexed", func(b *testing.B) {
+		m := NewMatcher(rule)
+		b.ResetTimer()
+		for range b.N {
+			for _, n := range node {
+				_ = m.Match(n)
+			}
+		}
+	})
+}
+
+func matcherFixture(r *rand.Rand, rules, nodes int) (rule []When, node []Node) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	uuid := make([]UUID, rules/2+1)
+	for i := range uuid {
+		uuid[i] = MustUUID(fmt.Sprintf("%08x-0000-0000-0000-000000000000", i))
+	}
+	hook := func() Hook {
+		h := Hook{"kind": fmt.Sprint("k", r.IntN(50))}
+		if r.IntN(4) == 0 {
+			h["tag"] = []any{fmt.Sprint("t", r.IntN(5))}
+		}
+		return h
+	}
+	live := func() Live {
+		var l Live
+		if r.IntN(2) == 0 {
+			l.Since = option.Some(base.Add(time.Duration(r.IntN(100)) * time.Hour))
+		}
+		if r.IntN(2) == 0 {
+			l.Until = option.Some(base.Add(time.Duration(100+r.IntN(100)) * time.Hour))
+		}
+		return l
+	}
+	for range rules {
+		var w When
+		switch r.IntN(10) {
+		case 0, 1, 2, 3:
+			w.UUID = option.Some([]UUID{uuid[r.IntN(len(uuid))], uuid[r.IntN(len(uuid))]})
+			if r.IntN(3) == 0 {
+				w.Hook = option.Some([]Hook{hook()})
+			}
+		case 4, 5, 6:
+			w.Hook = option.Some([]Hook{hook(), hook()})
+		case 7, 8:
+			w.Live = option.Some([]Live{live()})
+		default:
+			if r.IntN(2) == 0 {
+				w.UUID = option.None[[]UUID]()
+			}
+		}
+		rule = append(rule, w)
+	}
+	for range nodes {
+		var n Node
+		if r.IntN(5) != 0 {
+			n.UUID = option.Some(uuid[r.IntN(len(uuid))])
+		}
+		if r.IntN(3) != 0 {
+			h := hook()
+			h["tag"] = []any{"t0", fmt.Sprint("t", r.IntN(5))}
+			n.Hook = option.Some(h)
+		}
+		if r.IntN(3) != 0 {
+			n.Live = option.Some(live())
+		}
+		node = append(node, n)
+	}
+	return slices.Clip(rule), node
+}
@@ -9,7 +9,7 @@ import (
 type Meta map[string]any
 
 func (it Meta) Equal(t Meta) bool {
-	return deepEqual(it, t)
+	return deepEqual(map[string]any(it), map[string]any(t))
 }
 func (it Meta) With(pp Meta) Meta {
 	if len(it) == 0 {
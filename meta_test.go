@@ -62,3 +62,7 @@ func TestMetaLog(t *testing.T) {
 		b.Reset()
 	})
 }
+func TestMetaEqual(t *testing.T) {
+	require.True(t, Meta{"a": map[string]any{"b": "1"}}.Equal(Meta{"a": map[string]any{"b": "1"}}))
+	require.False(t, Meta{"a": "1"}.Equal(Meta{}))
+}
@@ -2,17 +2,31 @@ package flow
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
+
+	jsoniter "github.com/json-iterator/go"
 )
 
+var ErrUnexpectedType = errors.New("unexpected type")
+
 func nextIf(target []Node, next Next, predicat func(Node) bool) (err error) {
+	return nextIndex(target, next, func(i int) bool {
+		return predicat(target[i])
+	})
+}
+func nextIndex(target []Node, next Next, predicat func(int) bool) (err error) {
+	if len(target) == 0 {
+		return nil
+	}
 	var errs []error
 	defer getSliceError(&errs)
 	var min, max int
 	for {
-		if max < len(target) && predicat(target[max]) {
+		if max < len(target) && predicat(max) {
 			max++
 			continue
 		}
@@ -31,6 +45,24 @@ func nextIf(target []Node, next Next, predicat func(Node) bool) (err error) {
 	}
 	return errors.Join(errs...)
 }
+func timeoutName(ctx context.Context, name string, running *atomic.Pointer[string]) string {
+	if name != "" {
+		return name
+	}
+	if p := running.Load(); p != nil {
+		return *p
+	}
+	if name = PipeName(ctx); name != "" {
+		return name
+	}
+	return "handler"
+}
+func unmarshalRaw(b jsoniter.RawMessage, v any) error {
+	if len(b) == 0 {
+		return nil
+	}
+	return jsoniter.Unmarshal(b, v)
+}
 func deepCopy(v any) any {
 	switch v := v.(type) {
 	case int, int8, int16, int32, int64,
@@ -52,7 +84,7 @@ func deepCopy(v any) any {
 	case time.Time:
 		return v
 	default:
-		panic(fmt.Sprintf("unexpected type: %T", v))
+		panic(fmt.Errorf("%w: %T", ErrUnexpectedType, v))
 	}
 }
 func deepWith(l, r any, merge bool) any {
@@ -195,7 +227,7 @@ func deepEqual[T1, T2 any](l T1, r T2) bool {
 			return true
 		}
 	default:
-		panic(fmt.Sprintf("unexpected type: %T", l))
+		panic(fmt.Errorf("%w: %T", ErrUnexpectedType, l))
 	}
 
 	return false
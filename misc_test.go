@@ -106,3 +106,16 @@ func TestNextif_match_last(t *testing.T) {
 	require.Zero(t, target[1].UUID)
 	require.Equal(t, "14725bfb-6562-4f14-8841-df255fa9082a", target[2].UUID.Get().String())
 }
+func TestNextif_empty(t *testing.T) {
+	called := false
+	next := Next(func(target []Node) error {
+		called = true
+		return nil
+	})
+	predicate := func(n Node) bool {
+		return true
+	}
+	err := nextIf(nil, next, predicate)
+	require.NoError(t, err)
+	require.False(t, called)
+}
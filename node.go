@@ -201,8 +201,10 @@ func (it Node) MarshalJSON() (b []byte, err error) {
 	if js.Live, err = jsoniter.Marshal(it.Live); err != nil {
 		return nil, fmt.Errorf("live: %w", err)
 	}
-	if js.Origin, err = jsoniter.Marshal(it.origin); err != nil {
-		return nil, fmt.Errorf("origin: %w", err)
+	if it.origin != nil {
+		if js.Origin, err = jsoniter.Marshal(it.origin); err != nil {
+			return nil, fmt.Errorf("origin: %w", err)
+		}
 	}
 	return jsoniter.Marshal(js)
 }
@@ -211,20 +213,24 @@ func (it *Node) UnmarshalJSON(b []byte) (err error) {
 	if err = jsoniter.Unmarshal(b, &js); err != nil {
 		return err
 	}
-	if err = jsoniter.Unmarshal(js.UUID, &it.UUID); err != nil {
+	if err = unmarshalRaw(js.UUID, &it.UUID); err != nil {
 		return fmt.Errorf("uuid: %w", err)
 	}
-	if err = jsoniter.Unmarshal(js.Meta, &it.Meta); err != nil {
+	if err = unmarshalRaw(js.Meta, &it.Meta); err != nil {
 		return fmt.Errorf("meta: %w", err)
 	}
-	if err = jsoniter.Unmarshal(js.Hook, &it.Hook); err != nil {
+	if err = unmarshalRaw(js.Hook, &it.Hook); err != nil {
 		return fmt.Errorf("hook: %w", err)
 	}
-	if err = jsoniter.Unmarshal(js.Live, &it.Live); err != nil {
+	if err = unmarshalRaw(js.Live, &it.Live); err != nil {
 		return fmt.Errorf("live: %w", err)
 	}
-	if err = jsoniter.Unmarshal(js.Origin, it.origin); err != nil {
-		return fmt.Errorf("origin: %w", err)
+	if len(js.Origin) != 0 && string(js.Origin) != "null" {
+		var origin Node
+		if err = unmarshalRaw(js.Origin, &origin); err != nil {
+			return fmt.Errorf("origin: %w", err)
+		}
+		it.SetOrigin(origin)
 	}
 	return nil
 }
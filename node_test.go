@@ -99,3 +99,47 @@ func TestNodeLog(t *testing.T) {
 		b.Reset()
 	})
 }
+func TestNodeJSON(t *testing.T) {
+	t.Run("zero", func(t *testing.T) {
+		v := Node{}
+		b, err := v.MarshalJSON()
+		require.NoError(t, err)
+		require.JSONEq(t, `{}`, string(b))
+		e := Node{}
+		err = e.UnmarshalJSON(b)
+		require.NoError(t, err)
+		require.Equal(t, v, e)
+	})
+	t.Run("some", func(t *testing.T) {
+		v := Node{
+			UUID: option.Some(MustUUID("85432856-ba6c-46d3-9fcf-05650bfd5814")),
+			Meta: option.Some(Meta{"b": "2"}),
+			Hook: option.Some(Hook{"a": "1"}),
+			Live: option.Some(Live{Since: option.Some(time.Unix(0, 0).UTC())}),
+		}
+		v.SetOrigin(Node{
+			UUID: option.Some(MustUUID("85432856-ba6c-46d3-9fcf-05650bfd5814")),
+			Hook: option.Some(Hook{"a": "0"}),
+		})
+		b, err := v.MarshalJSON()
+		require.NoError(t, err)
+		require.JSONEq(t,
+			`{
+				"uuid":"85432856-ba6c-46d3-9fcf-05650bfd5814",
+				"meta":{"b":"2"},
+				"hook":{"a":"1"},
+				"live":{"since":"1970-01-01T00:00:00Z"},
+				"origin":{
+					"uuid":"85432856-ba6c-46d3-9fcf-05650bfd5814",
+					"hook":{"a":"0"}
+				}
+			}`,
+			string(b),
+		)
+		e := Node{}
+		err = e.UnmarshalJSON(b)
+		require.NoError(t, err)
+		require.True(t, v.Equal(e))
+		require.True(t, v.Origin().Equal(e.Origin()))
+	})
+}
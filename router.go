@@ -0,0 +1,83 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/typomaker/option"
+)
+
+type Route struct {
+	When     When
+	Handler  Handler
+	Priority option.Option[int]
+}
+
+func (it Route) priority() int {
+	if it.Priority.IsSome() {
+		return it.Priority.Get()
+	}
+	return it.When.Specificity()
+}
+
+func Router(r ...Route) Handler {
+	r = slices.Clone(r)
+	return func(ctx context.Context, target []Node, next Next) (err error) {
+		var owner = make([]int, len(target))
+		for i := range target {
+			owner[i] = slices.IndexFunc(r, func(r Route) bool {
+				return target[i].When(r.When)
+			})
+		}
+		var errs []error
+		for j := range r {
+			err = nextIndex(target, func(target []Node) error {
+				return r[j].Handler(ctx, target, next)
+			}, func(i int) bool {
+				return owner[i] == j
+			})
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		// nodes no route matches pass through untouched
+		if err = nextIndex(target, next, func(i int) bool { return owner[i] < 0 }); err != nil {
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	}
+}
+
+func Prioritize(r ...Route) Handler {
+	return Router(sortRoute(r)...)
+}
+func sortRoute(r []Route) []Route {
+	r = slices.Clone(r)
+	slices.SortStableFunc(r, func(a, b Route) int {
+		return b.priority() - a.priority()
+	})
+	return r
+}
+
+type _RouteJSON struct {
+	When    When   `json:"when"`
+	Handler string `json:"handler"`
+}
+
+func LoadRouter(b []byte, resolve func(name string) (Handler, error)) (_ Handler, err error) {
+	var js []_RouteJSON
+	if err = jsoniter.Unmarshal(b, &js); err != nil {
+		return nil, fmt.Errorf("flow: router: %w", err)
+	}
+	var r = make([]Route, len(js))
+	for i := range js {
+		r[i].When = js[i].When
+		if r[i].Handler, err = resolve(js[i].Handler); err != nil {
+			return nil, fmt.Errorf("flow: route %d: %w", i, err)
+		}
+	}
+	return Router(r...), nil
+}
@@ -0,0 +1,93 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/typomaker/option"
+)
+
+func TestRouter(t *testing.T) {
+	mark := func(name string) Handler {
+		return func(ctx context.Context, target []Node, next Next) (err error) {
+			for i := range target {
+				target[i].Meta = option.Some(Meta{"route": name})
+			}
+			return next(target)
+		}
+	}
+	cat := MustUUID("10000000-0000-0000-0000-000000000000")
+	f := Router(
+		Route{When: When{UUID: option.Some([]UUID{cat})}, Handler: mark("uuid")},
+		Route{When: When{Hook: option.Some([]Hook{{"kind": "cat"}})}, Handler: mark("cat")},
+		Route{When: When{Hook: option.Some([]Hook{{"kind": "dog"}})}, Handler: mark("dog")},
+	)
+	target := []Node{
+		{Hook: option.Some(Hook{"kind": "cat"})},
+		{Hook: option.Some(Hook{"kind": "cat"})},
+		{UUID: option.Some(cat), Hook: option.Some(Hook{"kind": "cat"})},
+		{Hook: option.Some(Hook{"kind": "dog"})},
+		{Hook: option.Some(Hook{"kind": "cat"})},
+		{Hook: option.Some(Hook{"kind": "bird"})},
+	}
+	var group [][]Node
+	err := f(context.Background(), target, func(target []Node) error {
+		group = append(group, target)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 1, 1, 1}, []int{len(group[0]), len(group[1]), len(group[2]), len(group[3]), len(group[4])})
+	require.Equal(t, []Node{{Hook: option.Some(Hook{"kind": "bird"})}}, group[4])
+	route := make([]any, len(target))
+	for i := range target {
+		route[i] = target[i].Meta.GetOrZero()["route"]
+	}
+	require.Equal(t, []any{"cat", "cat", "uuid", "dog", "cat", nil}, route)
+}
+func TestRouterError(t *testing.T) {
+	a, b := errors.New("a"), errors.New("b")
+	fail := func(err error) Handler {
+		return func(ctx context.Context, target []Node, next Next) error { return err }
+	}
+	err := Router(
+		Route{When: When{Hook: option.Some([]Hook{{"kind": "cat"}})}, Handler: fail(a)},
+		Route{When: When{}, Handler: fail(b)},
+	)(context.Background(), []Node{{Hook: option.Some(Hook{"kind": "cat"})}, {}}, noopNext)
+	require.ErrorIs(t, err, a)
+	require.ErrorIs(t, err, b)
+
+	err = Router()(context.Background(), nil, noopNext)
+	require.NoError(t, err)
+}
+func TestLoadRouter(t *testing.T) {
+	var seen []string
+	resolve := func(name string) (Handler, error) {
+		if name == "missing.js" {
+			return nil, errors.New("not found")
+		}
+		return func(ctx context.Context, target []Node, next Next) (err error) {
+			seen = append(seen, name)
+			return next(target)
+		}, nil
+	}
+	f, err := LoadRouter([]byte(`[
+		{"when": {"hook": [{"kind": "cat"}]}, "handler": "cat.js"},
+		{"when": {"uuid": ["20000000-0000-0000-0000-000000000000"]}, "handler": "dog.js"},
+		{"when": {}, "handler": "rest.js"}
+	]`), resolve)
+	require.NoError(t, err)
+	err = f(context.Background(), []Node{
+		{Hook: option.Some(Hook{"kind": "cat"})},
+		{UUID: option.Some(MustUUID("20000000-0000-0000-0000-000000000000"))},
+		{},
+	}, noopNext)
+	require.NoError(t, err)
+	require.Equal(t, []string{"cat.js", "dog.js", "rest.js"}, seen)
+
+	_, err = LoadRouter([]byte(`[{"when": {}, "handler": "missing.js"}]`), resolve)
+	require.EqualError(t, err, "flow: route 0: not found")
+	_, err = LoadRouter([]byte(`{`), resolve)
+	require.Error(t, err)
+}
@@ -59,6 +59,7 @@ func (it Then) LogValue() slog.Value {
 
 type _ThenJSON struct {
 	Kind jsoniter.RawMessage `json:"kind,omitempty"`
+	Meta jsoniter.RawMessage `json:"meta,omitempty"`
 	Hook jsoniter.RawMessage `json:"hook,omitempty"`
 	Live jsoniter.RawMessage `json:"live,omitempty"`
 }
@@ -68,6 +69,9 @@ func (it Then) MarshalJSON() (b []byte, err error) {
 	if js.Kind, err = jsoniter.Marshal(it.Kind); err != nil {
 		return nil, fmt.Errorf("kind: %w", err)
 	}
+	if js.Meta, err = jsoniter.Marshal(it.Meta); err != nil {
+		return nil, fmt.Errorf("meta: %w", err)
+	}
 	if js.Hook, err = jsoniter.Marshal(it.Hook); err != nil {
 		return nil, fmt.Errorf("hook: %w", err)
 	}
@@ -81,13 +85,16 @@ func (it *Then) UnmarshalJSON(b []byte) (err error) {
 	if err = jsoniter.Unmarshal(b, &js); err != nil {
 		return err
 	}
-	if err = jsoniter.Unmarshal(js.Kind, &it.Kind); err != nil {
+	if err = unmarshalRaw(js.Kind, &it.Kind); err != nil {
 		return fmt.Errorf("kind: %w", err)
 	}
-	if err = jsoniter.Unmarshal(js.Hook, &it.Hook); err != nil {
+	if err = unmarshalRaw(js.Meta, &it.Meta); err != nil {
+		return fmt.Errorf("meta: %w", err)
+	}
+	if err = unmarshalRaw(js.Hook, &it.Hook); err != nil {
 		return fmt.Errorf("hook: %w", err)
 	}
-	if err = jsoniter.Unmarshal(js.Live, &it.Live); err != nil {
+	if err = unmarshalRaw(js.Live, &it.Live); err != nil {
 		return fmt.Errorf("live: %w", err)
 	}
 	return nil
@@ -84,3 +84,19 @@ func TestThenLog(t *testing.T) {
 		b.Reset()
 	})
 }
+func TestThenJSON(t *testing.T) {
+	v := Then{}
+	err := v.UnmarshalJSON([]byte(`{"kind":"foo"}`))
+	require.NoError(t, err)
+	require.Equal(t, Then{Kind: option.Some[Kind]("foo")}, v)
+}
+func TestThenJSONMeta(t *testing.T) {
+	v := Then{Kind: option.Some[Kind]("foo"), Meta: option.Some(Meta{"a": "1"})}
+	b, err := v.MarshalJSON()
+	require.NoError(t, err)
+	require.JSONEq(t, `{"kind":"foo","meta":{"a":"1"}}`, string(b))
+	e := Then{}
+	err = e.UnmarshalJSON(b)
+	require.NoError(t, err)
+	require.True(t, v.Equal(e))
+}
@@ -39,6 +39,16 @@ func (it UUID) LogValue() slog.Value {
 func (it UUID) MarshalJSON() ([]byte, error) {
 	return jsoniter.Marshal(it.String())
 }
+func (it *UUID) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err = jsoniter.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if *it, err = ParseUUID(s); err != nil {
+		return err
+	}
+	return nil
+}
 func (it UUID) String() string {
 	return uuid.UUID(it).String()
 }
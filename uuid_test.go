@@ -0,0 +1,19 @@
+package flow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUUIDJSON(t *testing.T) {
+	v := MustUUID("85432856-ba6c-46d3-9fcf-05650bfd5814")
+	b, err := v.MarshalJSON()
+	require.NoError(t, err)
+	e := UUID{}
+	err = e.UnmarshalJSON(b)
+	require.NoError(t, err)
+	require.Equal(t, v, e)
+	err = e.UnmarshalJSON([]byte(`"foo"`))
+	require.Error(t, err)
+}
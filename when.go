@@ -15,6 +15,18 @@ type When struct {
 	Live option.Option[[]Live] `json:"live,omitempty"`
 }
 
+func (it When) Specificity() (v int) {
+	if it.UUID.IsSome() {
+		v += 4
+	}
+	if it.Hook.IsSome() {
+		v += 2
+	}
+	if it.Live.IsSome() {
+		v += 1
+	}
+	return v
+}
 func (it When) Equal(t When) bool {
 	switch {
 	case !slices.Equal(it.UUID.GetOrZero(), t.UUID.GetOrZero()):
@@ -74,13 +86,13 @@ func (it *When) UnmarshalJSON(b []byte) (err error) {
 	if err = jsoniter.Unmarshal(b, &js); err != nil {
 		return err
 	}
-	if err = jsoniter.Unmarshal(js.UUID, &it.UUID); err != nil {
+	if err = unmarshalRaw(js.UUID, &it.UUID); err != nil {
 		return fmt.Errorf("uuid: %w", err)
 	}
-	if err = jsoniter.Unmarshal(js.Hook, &it.Hook); err != nil {
+	if err = unmarshalRaw(js.Hook, &it.Hook); err != nil {
 		return fmt.Errorf("hook: %w", err)
 	}
-	if err = jsoniter.Unmarshal(js.Live, &it.Live); err != nil {
+	if err = unmarshalRaw(js.Live, &it.Live); err != nil {
 		return fmt.Errorf("live: %w", err)
 	}
 	return nil
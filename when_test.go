@@ -78,3 +78,9 @@ func TestWhenLog(t *testing.T) {
 		b.Reset()
 	})
 }
+func TestWhenJSON(t *testing.T) {
+	v := When{}
+	err := v.UnmarshalJSON([]byte(`{"hook":[{"a":"1"}]}`))
+	require.NoError(t, err)
+	require.Equal(t, When{Hook: option.Some([]Hook{{"a": "1"}})}, v)
+}